@@ -0,0 +1,190 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/compose-spec/compose-go/loader"
+	"github.com/compose-spec/compose-go/types"
+)
+
+// ProjectBuilder builds a project from typed values instead of yaml.
+//
+// Build marshals added values into a compose file and loads it by loader.LoadWithContext,
+// thus the project is validated against the compose spec and normalized exactly as one loaded from yaml,
+// e.g. relative paths are resolved against the working directory and default networks are added.
+// Values are not interpolated, since they are already literal.
+//
+// Methods record the first error, which is returned by Build and ConfigDetails.
+type ProjectBuilder struct {
+	name        string
+	workingDir  string
+	environment types.Mapping
+	profiles    []string
+	project     types.Project
+	err         error
+}
+
+// NewProjectBuilder returns a new ProjectBuilder for the project named name.
+func NewProjectBuilder(name string) *ProjectBuilder {
+	return &ProjectBuilder{name: name}
+}
+
+func (b *ProjectBuilder) setErr(format string, args ...any) *ProjectBuilder {
+	if b.err == nil {
+		b.err = fmt.Errorf(format, args...)
+	}
+	return b
+}
+
+// WorkingDir sets the working directory which relative paths, e.g. build contexts and env_file, are resolved against.
+// It defaults to the current directory.
+func (b *ProjectBuilder) WorkingDir(dir string) *ProjectBuilder {
+	b.workingDir = dir
+	return b
+}
+
+// Environment sets environment variables of the project, which are used to resolve environment of services.
+// It defaults to os.Environ().
+func (b *ProjectBuilder) Environment(env types.Mapping) *ProjectBuilder {
+	b.environment = env.Clone()
+	return b
+}
+
+// Profiles sets active profiles. Services whose profiles are not active are disabled as the loader does.
+func (b *ProjectBuilder) Profiles(profiles ...string) *ProjectBuilder {
+	b.profiles = slices.Clone(profiles)
+	return b
+}
+
+// Service adds service. service.Name must not be empty nor duplicate.
+func (b *ProjectBuilder) Service(service types.ServiceConfig) *ProjectBuilder {
+	if service.Name == "" {
+		return b.setErr("service name must not be empty")
+	}
+	if slices.ContainsFunc(b.project.Services, func(s types.ServiceConfig) bool { return s.Name == service.Name }) {
+		return b.setErr("service %q is already added", service.Name)
+	}
+	b.project.Services = append(b.project.Services, service)
+	return b
+}
+
+// DependsOn adds dependency to depends_on of service with condition, e.g. types.ServiceConditionHealthy.
+// condition defaults to types.ServiceConditionStarted. service must be added beforehand.
+func (b *ProjectBuilder) DependsOn(service, dependency, condition string) *ProjectBuilder {
+	idx := slices.IndexFunc(b.project.Services, func(s types.ServiceConfig) bool { return s.Name == service })
+	if idx < 0 {
+		return b.setErr("service %q is not added", service)
+	}
+	if condition == "" {
+		condition = types.ServiceConditionStarted
+	}
+	s := &b.project.Services[idx]
+	if s.DependsOn == nil {
+		s.DependsOn = types.DependsOnConfig{}
+	}
+	s.DependsOn[dependency] = types.ServiceDependency{Condition: condition, Required: true}
+	return b
+}
+
+// Network adds network named name.
+func (b *ProjectBuilder) Network(name string, network types.NetworkConfig) *ProjectBuilder {
+	if b.project.Networks == nil {
+		b.project.Networks = types.Networks{}
+	}
+	b.project.Networks[name] = network
+	return b
+}
+
+// Volume adds volume named name.
+func (b *ProjectBuilder) Volume(name string, volume types.VolumeConfig) *ProjectBuilder {
+	if b.project.Volumes == nil {
+		b.project.Volumes = types.Volumes{}
+	}
+	b.project.Volumes[name] = volume
+	return b
+}
+
+// Secret adds secret named name.
+func (b *ProjectBuilder) Secret(name string, secret types.SecretConfig) *ProjectBuilder {
+	if b.project.Secrets == nil {
+		b.project.Secrets = types.Secrets{}
+	}
+	b.project.Secrets[name] = secret
+	return b
+}
+
+// Config adds config named name.
+func (b *ProjectBuilder) Config(name string, config types.ConfigObjConfig) *ProjectBuilder {
+	if b.project.Configs == nil {
+		b.project.Configs = types.Configs{}
+	}
+	b.project.Configs[name] = config
+	return b
+}
+
+// ConfigDetails returns types.ConfigDetails holding a preloaded compose file of the project,
+// which can be passed to NewLoader or NewLoaderProxy.
+// Active profiles are not part of it and must be passed as loader options, e.g. loader.WithProfiles.
+// Options should also skip interpolation, otherwise "$" in values is interpolated.
+func (b *ProjectBuilder) ConfigDetails() (types.ConfigDetails, error) {
+	if b.err != nil {
+		return types.ConfigDetails{}, b.err
+	}
+	if b.name == "" {
+		return types.ConfigDetails{}, fmt.Errorf("project name must not be empty")
+	}
+	workingDir, err := filepath.Abs(b.workingDir)
+	if err != nil {
+		return types.ConfigDetails{}, err
+	}
+	env := b.environment
+	if env == nil {
+		env = types.NewMapping(os.Environ())
+	}
+
+	project := b.project
+	project.Name = b.name
+	if project.Services == nil {
+		// services is required by the compose spec.
+		project.Services = types.Services{}
+	}
+	content, err := project.MarshalYAML()
+	if err != nil {
+		return types.ConfigDetails{}, err
+	}
+
+	return PreloadConfigDetails(types.ConfigDetails{
+		WorkingDir: workingDir,
+		ConfigFiles: []types.ConfigFile{
+			{Filename: filepath.Join(workingDir, "compose.yaml"), Content: content},
+		},
+		Environment: env.Clone(),
+	})
+}
+
+// Build loads the project built by b with options.
+// The returned project is ready to be passed to NewComposeService.
+func (b *ProjectBuilder) Build(ctx context.Context, options ...func(*loader.Options)) (*types.Project, error) {
+	details, err := b.ConfigDetails()
+	if err != nil {
+		return nil, err
+	}
+	return loader.LoadWithContext(
+		ctx,
+		details,
+		append(
+			slices.Clone(options),
+			func(o *loader.Options) {
+				o.SkipInterpolation = true
+				if len(b.profiles) > 0 {
+					o.Profiles = b.profiles
+				}
+				o.SetProjectName(b.name, true)
+			},
+		)...,
+	)
+}
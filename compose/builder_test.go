@@ -0,0 +1,100 @@
+package compose
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectBuilder(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir := t.TempDir()
+	project, err := NewProjectBuilder("built").
+		WorkingDir(dir).
+		Environment(types.Mapping{"FOO": "foo"}).
+		Profiles("debug").
+		Service(types.ServiceConfig{
+			Name:        "db",
+			Image:       "postgres:16",
+			Environment: types.MappingWithEquals{"FOO": nil},
+			Volumes: []types.ServiceVolumeConfig{
+				{Type: types.VolumeTypeVolume, Source: "data", Target: "/var/lib/postgresql/data"},
+			},
+		}).
+		Service(types.ServiceConfig{
+			Name:     "app",
+			Image:    "ubuntu:jammy-20230624",
+			Command:  types.ShellCommand{"echo", "$HOME"},
+			Build:    &types.BuildConfig{Context: "./app"},
+			Networks: map[string]*types.ServiceNetworkConfig{"backend": nil},
+			Secrets:  []types.ServiceSecretConfig{{Source: "token"}},
+		}).
+		Service(types.ServiceConfig{
+			Name:     "debugger",
+			Image:    "ubuntu:jammy-20230624",
+			Profiles: []string{"debug"},
+		}).
+		Service(types.ServiceConfig{
+			Name:     "profiler",
+			Image:    "ubuntu:jammy-20230624",
+			Profiles: []string{"profile"},
+		}).
+		DependsOn("app", "db", types.ServiceConditionHealthy).
+		Network("backend", types.NetworkConfig{}).
+		Volume("data", types.VolumeConfig{}).
+		Secret("token", types.SecretConfig{File: "./token.txt"}).
+		Build(context.Background())
+	require.NoError(err)
+
+	assert.Equal("built", project.Name)
+	assert.Equal(dir, project.WorkingDir)
+	assert.ElementsMatch([]string{"app", "db", "debugger"}, project.ServiceNames())
+	require.Len(project.DisabledServices, 1)
+	assert.Equal("profiler", project.DisabledServices[0].Name)
+
+	app, err := project.GetService("app")
+	require.NoError(err)
+	// not interpolated.
+	assert.Equal(types.ShellCommand{"echo", "$HOME"}, app.Command)
+	// paths are resolved and defaults are filled as the loader does.
+	assert.Equal(filepath.Join(dir, "app"), app.Build.Context)
+	assert.Equal("Dockerfile", app.Build.Dockerfile)
+	assert.Equal(filepath.Join(dir, "token.txt"), project.Secrets["token"].File)
+	assert.Equal("built_backend", project.Networks["backend"].Name)
+	assert.Equal("built_data", project.Volumes["data"].Name)
+	assert.Equal(types.ServiceDependency{Condition: types.ServiceConditionHealthy, Required: true}, app.DependsOn["db"])
+
+	db, err := project.GetService("db")
+	require.NoError(err)
+	assert.Equal("foo", *db.Environment["FOO"])
+	// default network is added to services without networks.
+	assert.Contains(db.Networks, "default")
+
+	_, err = NewProjectBuilder("invalid").
+		Service(types.ServiceConfig{Name: "app", Image: "ubuntu:jammy-20230624"}).
+		Service(types.ServiceConfig{Name: "app", Image: "ubuntu:jammy-20230624"}).
+		Build(context.Background())
+	assert.Error(err)
+
+	_, err = NewProjectBuilder("invalid").
+		DependsOn("app", "db", "").
+		Build(context.Background())
+	assert.Error(err)
+
+	// validated as the loader does.
+	_, err = NewProjectBuilder("invalid").
+		Service(types.ServiceConfig{Name: "app", Image: "ubuntu:jammy-20230624", Secrets: []types.ServiceSecretConfig{{Source: "missing"}}}).
+		Build(context.Background())
+	assert.Error(err)
+	_, err = NewProjectBuilder("invalid").
+		Service(types.ServiceConfig{Name: "app", Image: "ubuntu:jammy-20230624"}).
+		DependsOn("app", "db", "").
+		Build(context.Background())
+	assert.Error(err)
+}
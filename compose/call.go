@@ -0,0 +1,283 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/cli/cli/streams"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/compose/v2/pkg/progress"
+)
+
+// projectResources is the lock key of resources shared among services of the project,
+// i.e. networks, volumes and orphan containers.
+// It never collides with service names since they can not be empty.
+const projectResources = ""
+
+// projectImages is the lock key of images of the project, which Build, Pull and Push serialize on.
+// It never collides with service names since they can not contain ":".
+const projectImages = ":images"
+
+// serviceLocks serializes mutating calls on overlapping services.
+type serviceLocks struct {
+	mu    sync.Mutex
+	locks map[string]chan struct{}
+}
+
+func newServiceLocks() *serviceLocks {
+	return &serviceLocks{locks: make(map[string]chan struct{})}
+}
+
+func (l *serviceLocks) get(name string) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ch, ok := l.locks[name]
+	if !ok {
+		ch = make(chan struct{}, 1)
+		l.locks[name] = ch
+	}
+	return ch
+}
+
+// lock locks names in sorted order, so that calls locking overlapping names never deadlock.
+// If ctx is cancelled while waiting, lock releases names locked so far and returns ctx.Err().
+func (l *serviceLocks) lock(ctx context.Context, names []string) (unlock func(), err error) {
+	names = slices.Clone(names)
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	var locked []chan struct{}
+	unlock = func() {
+		for i := len(locked) - 1; i >= 0; i-- {
+			<-locked[i]
+		}
+	}
+	for _, name := range names {
+		ch := l.get(name)
+		select {
+		case ch <- struct{}{}:
+			locked = append(locked, ch)
+		case <-ctx.Done():
+			unlock()
+			return nil, ctx.Err()
+		}
+	}
+	return unlock, nil
+}
+
+// callCli overrides output streams of a docker cli so that compose writes output of a call to the call's own buffers.
+type callCli struct {
+	command.Cli
+	out *streams.Out
+	err io.Writer
+}
+
+func (c *callCli) Out() *streams.Out { return c.out }
+func (c *callCli) Err() io.Writer    { return c.err }
+
+// call is the state of a single call to ComposeService.
+// It captures output of compose by its own, so that calls can run concurrently.
+type call struct {
+	s           *ComposeService
+	projectName string
+	// project is a snapshot of the wrapped project. The call and compose may freely mutate it.
+	project  *types.Project
+	dryRun   bool
+	cli      command.Cli
+	service  api.Service
+	out, err *progressWriter
+	notifier *progressNotifier
+	unlock   func()
+}
+
+// begin starts a call with ctx. The returned context must be passed to compose,
+// so that it reports dry run mode if s is the dry run view.
+// The call must be ended by end.
+func (s *ComposeService) begin(ctx context.Context) (*call, context.Context) {
+	s.mu.Lock()
+	cli, dryRun := s.cli, s.dryRun
+	s.mu.Unlock()
+
+	c := &call{
+		s:           s,
+		projectName: s.projectName,
+		project:     s.snapshot(),
+		dryRun:      dryRun,
+		notifier:    &progressNotifier{},
+	}
+	c.notifier.set(ctx)
+	c.out = newProgressWriter(c.decodeProgressEvent, c.notifier)
+	c.err = newProgressWriter(c.decodeProgressEvent, c.notifier)
+	c.cli = &callCli{Cli: cli, out: streams.NewOut(c.out), err: c.err}
+	c.service = api.NewServiceProxy().WithService(s.newService(c.cli))
+
+	if dryRun {
+		ctx = context.WithValue(ctx, api.DryRunKey{}, true)
+	}
+	return c, ctx
+}
+
+// end releases locks taken by lock and stops progress notification.
+func (c *call) end() {
+	if c.unlock != nil {
+		c.unlock()
+		c.unlock = nil
+	}
+	c.notifier.reset()
+}
+
+// lock locks services until end is called, for a call which mutates them.
+// If services is empty, all services of the project are locked.
+// If dependencies is true, services which they depend on are locked as well.
+// extra names are locked as is, e.g. projectResources and names of orphan services.
+//
+// lock takes a fresh snapshot of the project after locking,
+// so that the call sees changes made by calls which held locks before.
+// Calls in dry run mode change nothing and lock nothing.
+func (c *call) lock(ctx context.Context, services []string, dependencies bool, extra ...string) error {
+	if c.dryRun {
+		return nil
+	}
+	names, err := lockedServices(c.project, services, dependencies)
+	if err != nil {
+		return err
+	}
+	return c.lockNames(ctx, append(names, extra...))
+}
+
+// lockImages locks images of the project until end is called, for a call which builds, pulls or pushes them.
+// It does not wait for calls mutating containers.
+// Calls in dry run mode lock nothing.
+func (c *call) lockImages(ctx context.Context) error {
+	if c.dryRun {
+		return nil
+	}
+	return c.lockNames(ctx, []string{projectImages})
+}
+
+func (c *call) lockNames(ctx context.Context, names []string) error {
+	var err error
+	c.unlock, err = c.s.locks.lock(ctx, names)
+	if err != nil {
+		return err
+	}
+	c.project = c.s.snapshot()
+	return nil
+}
+
+func lockedServices(project *types.Project, services []string, dependencies bool) ([]string, error) {
+	if len(services) == 0 {
+		return project.ServiceNames(), nil
+	}
+	if !dependencies {
+		return slices.Clone(services), nil
+	}
+	var names []string
+	err := project.WithServices(services, func(service types.ServiceConfig) error {
+		names = append(names, service.Name)
+		return nil
+	})
+	return names, err
+}
+
+// rejectBuild returns an error wrapping ErrDryRunUnsupported if c is in dry run mode and build is non nil.
+//
+// The dry run view only replaces the docker api client with the dry run client,
+// since compose's own dry run mode would write output to the process' stdout and stderr.
+// Without it, compose builds images through buildkit, which talks to the daemon directly.
+func (c *call) rejectBuild(build *api.BuildOptions) error {
+	if c.dryRun && build != nil {
+		return fmt.Errorf("%w: building images", ErrDryRunUnsupported)
+	}
+	return nil
+}
+
+// runWithProgress runs fn with a progress.Writer in the context,
+// which records typed events emitted by fn into the stderr output of c.
+func (c *call) runWithProgress(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(progress.WithContextWriter(ctx, &eventWriter{
+		w: c.err,
+		decode: func(event progress.Event) (ProgressEvent, error) {
+			return decodeEvent(event, c.projectName, c.project, c.dryRun)
+		},
+		dryRun: c.dryRun,
+	}))
+}
+
+// decodeProgressEvent is called while the compose service is writing output of c.
+func (c *call) decodeProgressEvent(line string) (ProgressEvent, error) {
+	return DecodeProgressEvent(line, c.projectName, c.project, c.dryRun)
+}
+
+func (c *call) parseOutput() ComposeOutput {
+	c.out.flush()
+	c.err.flush()
+	out := ComposeOutput{
+		Resource: make(map[string]ComposeOutputLine),
+		Replicas: make(map[ResourceKey]ComposeOutputLine),
+		Out:      c.out.String(),
+		Err:      c.err.String(),
+	}
+	for _, event := range mergeEvents(c.out.Events(), c.err.Events()) {
+		out.AddEvent(event)
+	}
+	return out
+}
+
+// snapshot returns a copy of the wrapped project, which compose can mutate without affecting other calls.
+func (s *ComposeService) snapshot() *types.Project {
+	s.projectMu.RLock()
+	defer s.projectMu.RUnlock()
+	return cloneServices(s.project)
+}
+
+// cloneServices returns a shallow copy of project whose services have their own copy of fields which compose mutates.
+//
+// compose mutates them in place while running, e.g. ServiceHash overwrites deploy.replicas,
+// BuildOptions.Apply updates build, ensuring images adds labels to custom labels
+// and Restart and ForServices delete entries of depends_on.
+func cloneServices(project *types.Project) *types.Project {
+	cloned := *project
+	cloned.Services = slices.Clone(project.Services)
+	cloned.DisabledServices = slices.Clone(project.DisabledServices)
+	for i, service := range cloned.Services {
+		cloned.Services[i].DependsOn = maps.Clone(service.DependsOn)
+		if service.Deploy != nil {
+			deploy := *service.Deploy
+			cloned.Services[i].Deploy = &deploy
+		}
+		if service.Build != nil {
+			build := *service.Build
+			cloned.Services[i].Build = &build
+		}
+		cloned.Services[i].CustomLabels = maps.Clone(service.CustomLabels)
+	}
+	return &cloned
+}
+
+// forServices returns a copy of project in which only services are enabled,
+// along with services they depend on if dependencies is true.
+// project is returned as is if services is empty.
+//
+// compose converges or starts every service of the project it is given, e.g. in Create and Start,
+// not only ones in options.Services, thus calls pass the project narrowed to services they locked.
+func forServices(project *types.Project, services []string, dependencies bool) (*types.Project, error) {
+	if len(services) == 0 {
+		return project, nil
+	}
+	var option types.DependencyOption = types.IncludeDependencies
+	if !dependencies {
+		option = types.IgnoreDependencies
+	}
+	narrowed := cloneServices(project)
+	if err := narrowed.ForServices(services, option); err != nil {
+		return nil, err
+	}
+	return narrowed, nil
+}
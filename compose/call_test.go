@@ -0,0 +1,154 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceLocks(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	locks := newServiceLocks()
+	ctx := context.Background()
+
+	unlock, err := locks.lock(ctx, []string{"web", "db", "web"})
+	require.NoError(err)
+
+	// disjoint names are not blocked.
+	unlockOther, err := locks.lock(ctx, []string{"cache", projectResources, projectImages})
+	require.NoError(err)
+	unlockOther()
+
+	// overlapping names are blocked until unlocked.
+	locked := make(chan func())
+	go func() {
+		unlock, err := locks.lock(ctx, []string{"db", "worker"})
+		if err == nil {
+			locked <- unlock
+		}
+	}()
+	select {
+	case <-locked:
+		t.Fatal("overlapping lock must wait")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = locks.lock(timeout, []string{"worker", "web"})
+	assert.ErrorIs(err, context.DeadlineExceeded)
+
+	unlock()
+	select {
+	case unlock := <-locked:
+		unlock()
+	case <-time.After(time.Second):
+		t.Fatal("lock must be acquired after unlock")
+	}
+
+	// names locked by the cancelled call are released.
+	unlock, err = locks.lock(ctx, []string{"worker", "web", "db"})
+	require.NoError(err)
+	unlock()
+}
+
+func TestLockedServices(t *testing.T) {
+	assert := assert.New(t)
+	project := loadFromString(waitComposeYaml)
+
+	names, err := lockedServices(project, nil, false)
+	assert.NoError(err)
+	assert.ElementsMatch(project.ServiceNames(), names)
+
+	names, err = lockedServices(project, []string{"app"}, false)
+	assert.NoError(err)
+	assert.Equal([]string{"app"}, names)
+
+	names, err = lockedServices(project, []string{"app"}, true)
+	assert.NoError(err)
+	assert.ElementsMatch([]string{"app", "db", "migrate", "cache"}, names)
+
+	_, err = lockedServices(project, []string{"nonexistent"}, true)
+	assert.Error(err)
+}
+
+func TestCloneServices(t *testing.T) {
+	assert := assert.New(t)
+	project := loadFromString(waitComposeYaml)
+	AddDockerComposeLabel(project)
+
+	cloned := cloneServices(project)
+	for i := range cloned.Services {
+		cloned.Services[i].CustomLabels.Add(api.ImageDigestLabel, "sha256:digest")
+		if cloned.Services[i].Deploy != nil {
+			r := uint64(1)
+			cloned.Services[i].Deploy.Replicas = &r
+		}
+	}
+	worker, _ := project.GetService("worker")
+	assert.Equal(uint64(2), *worker.Deploy.Replicas)
+	for _, service := range project.Services {
+		_, ok := service.CustomLabels[api.ImageDigestLabel]
+		assert.False(ok)
+	}
+}
+
+func TestForServices(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	project := loadFromString(waitComposeYaml)
+
+	narrowed, err := forServices(project, nil, false)
+	require.NoError(err)
+	assert.Same(project, narrowed)
+
+	narrowed, err = forServices(project, []string{"app"}, true)
+	require.NoError(err)
+	assert.ElementsMatch([]string{"app", "db", "migrate", "cache"}, narrowed.ServiceNames())
+
+	narrowed, err = forServices(project, []string{"app"}, false)
+	require.NoError(err)
+	assert.Equal([]string{"app"}, narrowed.ServiceNames())
+	app, _ := narrowed.GetService("app")
+	assert.Empty(app.DependsOn)
+
+	// project is left as is.
+	assert.Len(project.ServiceNames(), 5)
+	assert.Empty(project.DisabledServices)
+	app, _ = project.GetService("app")
+	assert.Len(app.DependsOn, 3)
+
+	_, err = forServices(project, []string{"nonexistent"}, true)
+	assert.Error(err)
+}
+
+func TestCall_output(t *testing.T) {
+	require := require.New(t)
+	composeService, err := loaderAdditional.LoadComposeService(context.Background())
+	require.NoError(err)
+
+	var calls []*call
+	for i := 1; i <= 2; i++ {
+		c, _ := composeService.begin(context.Background())
+		defer c.end()
+		calls = append(calls, c)
+	}
+	for i, c := range calls {
+		fmt.Fprintf(c.cli.Err(), " Container %s-sample_service-%d  Created\n", composeService.projectName, i+1)
+	}
+
+	for i, c := range calls {
+		out := c.parseOutput()
+		require.Len(out.Replicas, 1)
+		for key, line := range out.Replicas {
+			require.Equal(i+1, key.Num)
+			require.Equal(Created, line.StateType)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package compose
 
 import (
+	"bytes"
+	"encoding/json"
+	"slices"
 	"strings"
 
 	"github.com/compose-spec/compose-go/types"
@@ -26,7 +29,7 @@ NEW_SERVICE:
 				continue NEW_SERVICE
 			}
 		}
-		addedInNew = append(addedInNew, fallbackLatest(newService.Name))
+		addedInNew = append(addedInNew, fallbackLatest(newService.Image))
 	}
 	return onlyInOld, addedInNew
 }
@@ -38,3 +41,242 @@ func fallbackLatest(i string) string {
 	}
 	return i + ":latest"
 }
+
+// ChangeKind is the kind of a change between 2 projects.
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// FieldChange is a change of a top level field of a service.
+type FieldChange struct {
+	// Field is the key of the field in the compose file, e.g. "environment", "ports", "volumes", "labels" and "command".
+	// Changes of deploy.replicas are reported separately as "deploy.replicas".
+	Field string
+	// Old and New are values of the field decoded from JSON. nil if the field is not set.
+	Old, New any
+	// ForcesRecreate reports whether the change alone causes compose to recreate containers.
+	ForcesRecreate bool
+}
+
+// ServiceDiff is a difference of a service between 2 projects.
+type ServiceDiff struct {
+	Name string
+	Kind ChangeKind
+	// Fields is set only if Kind is ChangeChanged. It is sorted by Field.
+	Fields []FieldChange
+	// ForcesRecreate reports whether compose would recreate containers of the service,
+	// i.e. whether the config hash labeled to containers differs.
+	// It is always false for added and removed services.
+	ForcesRecreate bool
+}
+
+// ResourceDiff is a difference of a network, volume, secret or config between 2 projects.
+type ResourceDiff struct {
+	Name string
+	Kind ChangeKind
+}
+
+// ProjectDiff is a difference between 2 projects. Each slice is sorted by name.
+type ProjectDiff struct {
+	Services []ServiceDiff
+	Networks []ResourceDiff
+	Volumes  []ResourceDiff
+	Secrets  []ResourceDiff
+	Configs  []ResourceDiff
+}
+
+// Empty reports whether no difference is found.
+func (d ProjectDiff) Empty() bool {
+	return len(d.Services) == 0 &&
+		len(d.Networks) == 0 &&
+		len(d.Volumes) == 0 &&
+		len(d.Secrets) == 0 &&
+		len(d.Configs) == 0
+}
+
+// Recreated returns names of services whose containers would be recreated.
+func (d ProjectDiff) Recreated() []string {
+	var names []string
+	for _, s := range d.Services {
+		if s.ForcesRecreate {
+			names = append(names, s.Name)
+		}
+	}
+	return names
+}
+
+// fieldsNotRecreating is fields compose excludes from the config hash.
+var fieldsNotRecreating = []string{"build", "pull_policy", "scale", "deploy.replicas"}
+
+// DiffProjects compares 2 projects and reports added, removed and changed services, networks, volumes, secrets and configs.
+//
+// Only enabled services are compared. Disabled services are treated as absent.
+// Services are compared by their fields in the compose file, while the config hash compose computes decides ForcesRecreate.
+func DiffProjects(old, new *types.Project) (ProjectDiff, error) {
+	var diff ProjectDiff
+
+	oldServices := make(map[string]types.ServiceConfig, len(old.Services))
+	for _, s := range old.Services {
+		oldServices[s.Name] = s
+	}
+	newServices := make(map[string]types.ServiceConfig, len(new.Services))
+	for _, s := range new.Services {
+		newServices[s.Name] = s
+	}
+	for _, name := range unionKeys(oldServices, newServices) {
+		oldService, inOld := oldServices[name]
+		newService, inNew := newServices[name]
+		switch {
+		case !inOld:
+			diff.Services = append(diff.Services, ServiceDiff{Name: name, Kind: ChangeAdded})
+		case !inNew:
+			diff.Services = append(diff.Services, ServiceDiff{Name: name, Kind: ChangeRemoved})
+		default:
+			serviceDiff, err := diffService(oldService, newService)
+			if err != nil {
+				return ProjectDiff{}, err
+			}
+			if len(serviceDiff.Fields) > 0 || serviceDiff.ForcesRecreate {
+				diff.Services = append(diff.Services, serviceDiff)
+			}
+		}
+	}
+
+	var err error
+	if diff.Networks, err = diffResources(old.Networks, new.Networks); err != nil {
+		return ProjectDiff{}, err
+	}
+	if diff.Volumes, err = diffResources(old.Volumes, new.Volumes); err != nil {
+		return ProjectDiff{}, err
+	}
+	if diff.Secrets, err = diffResources(old.Secrets, new.Secrets); err != nil {
+		return ProjectDiff{}, err
+	}
+	if diff.Configs, err = diffResources(old.Configs, new.Configs); err != nil {
+		return ProjectDiff{}, err
+	}
+	return diff, nil
+}
+
+func diffService(old, new types.ServiceConfig) (ServiceDiff, error) {
+	oldHash, err := serviceHash(old)
+	if err != nil {
+		return ServiceDiff{}, err
+	}
+	newHash, err := serviceHash(new)
+	if err != nil {
+		return ServiceDiff{}, err
+	}
+
+	oldFields, err := serviceFields(old)
+	if err != nil {
+		return ServiceDiff{}, err
+	}
+	newFields, err := serviceFields(new)
+	if err != nil {
+		return ServiceDiff{}, err
+	}
+
+	diff := ServiceDiff{Name: new.Name, Kind: ChangeChanged, ForcesRecreate: oldHash != newHash}
+	for _, field := range unionKeys(oldFields, newFields) {
+		if bytes.Equal(oldFields[field], newFields[field]) {
+			continue
+		}
+		var oldValue, newValue any
+		if oldFields[field] != nil {
+			if err := json.Unmarshal(oldFields[field], &oldValue); err != nil {
+				return ServiceDiff{}, err
+			}
+		}
+		if newFields[field] != nil {
+			if err := json.Unmarshal(newFields[field], &newValue); err != nil {
+				return ServiceDiff{}, err
+			}
+		}
+		diff.Fields = append(diff.Fields, FieldChange{
+			Field:          field,
+			Old:            oldValue,
+			New:            newValue,
+			ForcesRecreate: !slices.Contains(fieldsNotRecreating, field),
+		})
+	}
+	return diff, nil
+}
+
+// serviceFields returns top level fields of service encoded in JSON.
+// deploy.replicas is split from deploy.
+func serviceFields(service types.ServiceConfig) (map[string]json.RawMessage, error) {
+	var replicas *uint64
+	if service.Deploy != nil {
+		deploy := *service.Deploy
+		replicas, deploy.Replicas = deploy.Replicas, nil
+		service.Deploy = &deploy
+		if isZeroDeploy(deploy) {
+			service.Deploy = nil
+		}
+	}
+
+	bin, err := json.Marshal(service)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(bin, &fields); err != nil {
+		return nil, err
+	}
+	if replicas != nil {
+		fields["deploy.replicas"], _ = json.Marshal(*replicas)
+	}
+	return fields, nil
+}
+
+func isZeroDeploy(deploy types.DeployConfig) bool {
+	bin, err := json.Marshal(deploy)
+	if err != nil {
+		return false
+	}
+	zero, _ := json.Marshal(types.DeployConfig{})
+	return bytes.Equal(bin, zero)
+}
+
+func diffResources[T any](old, new map[string]T) ([]ResourceDiff, error) {
+	var diffs []ResourceDiff
+	for _, name := range unionKeys(old, new) {
+		oldResource, inOld := old[name]
+		newResource, inNew := new[name]
+		switch {
+		case !inOld:
+			diffs = append(diffs, ResourceDiff{Name: name, Kind: ChangeAdded})
+		case !inNew:
+			diffs = append(diffs, ResourceDiff{Name: name, Kind: ChangeRemoved})
+		default:
+			oldBin, err := json.Marshal(oldResource)
+			if err != nil {
+				return nil, err
+			}
+			newBin, err := json.Marshal(newResource)
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(oldBin, newBin) {
+				diffs = append(diffs, ResourceDiff{Name: name, Kind: ChangeChanged})
+			}
+		}
+	}
+	return diffs, nil
+}
+
+func unionKeys[V any](a, b map[string]V) []string {
+	keys := sortedKeys(a)
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
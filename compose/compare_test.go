@@ -81,3 +81,107 @@ func TestCompareProjectImage(t *testing.T) {
 		t.Errorf("not equal. diff = %s", diff)
 	}
 }
+
+func TestCompareProjectImage_addedInNew(t *testing.T) {
+	ctx := context.Background()
+	old, _ := loaderBase.Load(ctx)
+	newer, _ := loaderAdditional.Load(ctx)
+
+	onlyInOld, addedInNew := CompareProjectImage(old, newer)
+	if diff := cmp.Diff([]string(nil), onlyInOld); diff != "" {
+		t.Errorf("not equal. diff = %s", diff)
+	}
+	if diff := cmp.Diff([]string{"debian:bookworm-20230904"}, addedInNew); diff != "" {
+		t.Errorf("not equal. diff = %s", diff)
+	}
+}
+
+const diffOldComposeYaml = `services:
+  web:
+    image: ubuntu:jammy-20230624
+    command: ["sleep", "infinity"]
+    environment:
+      FOO: foo
+    ports:
+      - "8080:80"
+  worker:
+    image: ubuntu:jammy-20230624
+    deploy:
+      replicas: 1
+  removed:
+    image: ubuntu:jammy-20230624
+networks:
+  kept:
+  changed:
+volumes:
+  removed:
+`
+
+const diffNewComposeYaml = `services:
+  web:
+    image: ubuntu:jammy-20230624
+    command: ["sleep", "infinity"]
+    environment:
+      FOO: bar
+    ports:
+      - "8080:80"
+  worker:
+    image: ubuntu:jammy-20230624
+    deploy:
+      replicas: 3
+  added:
+    image: ubuntu:jammy-20230624
+networks:
+  kept:
+  changed:
+    internal: true
+configs:
+  added:
+    file: ./compose.yml
+`
+
+func TestDiffProjects(t *testing.T) {
+	old := loadFromString(diffOldComposeYaml)
+	newer := loadFromString(diffNewComposeYaml)
+
+	diff, err := DiffProjects(old, newer)
+	if err != nil {
+		t.Fatalf("err = %s", err)
+	}
+
+	expected := ProjectDiff{
+		Services: []ServiceDiff{
+			{Name: "added", Kind: ChangeAdded},
+			{Name: "removed", Kind: ChangeRemoved},
+			{
+				Name: "web", Kind: ChangeChanged, ForcesRecreate: true,
+				Fields: []FieldChange{
+					{Field: "environment", Old: map[string]any{"FOO": "foo"}, New: map[string]any{"FOO": "bar"}, ForcesRecreate: true},
+				},
+			},
+			{
+				Name: "worker", Kind: ChangeChanged,
+				Fields: []FieldChange{
+					{Field: "deploy.replicas", Old: float64(1), New: float64(3)},
+				},
+			},
+		},
+		Networks: []ResourceDiff{{Name: "changed", Kind: ChangeChanged}},
+		Volumes:  []ResourceDiff{{Name: "removed", Kind: ChangeRemoved}},
+		Configs:  []ResourceDiff{{Name: "added", Kind: ChangeAdded}},
+	}
+	if d := cmp.Diff(expected, diff); d != "" {
+		t.Errorf("not equal. diff = %s", d)
+	}
+	if d := cmp.Diff([]string{"web"}, diff.Recreated()); d != "" {
+		t.Errorf("not equal. diff = %s", d)
+	}
+
+	diff, err = DiffProjects(old, old)
+	if err != nil {
+		t.Fatalf("err = %s", err)
+	}
+	if !diff.Empty() {
+		t.Errorf("must be empty but is %+v", diff)
+	}
+}
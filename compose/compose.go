@@ -1,8 +1,9 @@
 package compose
 
 import (
-	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"strings"
 	"sync"
 
@@ -40,18 +41,36 @@ func AddDockerComposeLabel(project *types.Project) {
 	}
 }
 
+// ErrDryRunUnsupported is returned by calls to the dry run view which can not be run without changing anything.
+var ErrDryRunUnsupported = errors.New("not supported in dry run mode")
+
 type ComposeService struct {
-	mu          sync.Mutex
-	out, err    *bytes.Buffer
-	dryRun      bool
-	cli         command.Cli
+	// mu guards cli, dryRun and dryRunView.
+	mu         sync.Mutex
+	dryRun     bool
+	dryRunView *ComposeService
+	cli        command.Cli
+	// fields below are shared with the dry run view.
+	projectMu   *sync.RWMutex
+	locks       *serviceLocks
 	projectName string
 	project     *types.Project
-	service     api.Service
+	// newService returns the compose service which a call talks to through cli.
+	newService func(cli command.Cli) api.Service
 }
 
 // NewComposeService returns a new wrapped compose service proxy.
 // NewComposeService is not goroutine safe. It mutates given project.
+//
+// Methods of the returned ComposeService are safe to be called from multiple goroutines.
+// Each call captures the output of compose by its own.
+// Calls which only read the state, e.g. Ps, Logs and Events, never wait for other calls,
+// while calls which mutate containers wait for ones mutating the same services.
+// Such calls hand compose only services they wait for, e.g. Create with options.Services and their dependencies,
+// so that compose does not converge or start containers of other services.
+// Calls which may create or remove networks, volumes or orphan containers, e.g. Create, Up and Down,
+// are serialized among themselves.
+// Build, Pull and Push are serialized among themselves but never wait for calls mutating containers.
 func NewComposeService(
 	projectName string,
 	project *types.Project,
@@ -59,103 +78,207 @@ func NewComposeService(
 ) *ComposeService {
 	AddDockerComposeLabel(project)
 
-	var bufOut, bufErr = new(bytes.Buffer), new(bytes.Buffer)
-
-	serviceProxy := api.NewServiceProxy().WithService(compose.NewComposeService(dockerCli))
-
-	s := &ComposeService{
-		out:         bufOut,
-		err:         bufErr,
+	return &ComposeService{
 		cli:         dockerCli,
-		dryRun:      false,
-		service:     serviceProxy,
+		projectMu:   &sync.RWMutex{},
+		locks:       newServiceLocks(),
 		projectName: projectName,
 		project:     project,
+		newService:  compose.NewComposeService,
 	}
-	s.overrideOutputStreams()
-	return s
 }
 
-func (s *ComposeService) overrideOutputStreams() {
-	_ = s.cli.Apply(command.WithOutputStream(s.out), command.WithErrorStream(s.err))
+// Build executes the equivalent to a `compose build`
+//
+// options.Apply is applied to a copy of the wrapped project, so the project is left as is.
+//
+// The dry run view returns ErrDryRunUnsupported, since compose would run a real build through buildkit.
+func (s *ComposeService) Build(ctx context.Context, options api.BuildOptions) (ComposeOutput, error) {
+	c, ctx := s.begin(ctx)
+	defer c.end()
+	if err := c.rejectBuild(&options); err != nil {
+		return ComposeOutput{}, err
+	}
+	if err := c.lockImages(ctx); err != nil {
+		return ComposeOutput{}, err
+	}
+	err := c.service.Build(ctx, c.project, options)
+	return c.parseOutput(), err
 }
 
-func (s *ComposeService) resetBuf() {
-	s.out.Reset()
-	s.err.Reset()
+// Push executes the equivalent to a `compose push`
+func (s *ComposeService) Push(ctx context.Context, options api.PushOptions) (ComposeOutput, error) {
+	c, ctx := s.begin(ctx)
+	defer c.end()
+	if err := c.lockImages(ctx); err != nil {
+		return ComposeOutput{}, err
+	}
+	err := c.service.Push(ctx, c.project, options)
+	return c.parseOutput(), err
 }
 
-func (s *ComposeService) parseOutput() ComposeOutput {
-	out := ComposeOutput{}
-	out.ParseOutput(s.out.String(), s.err.String(), s.projectName, s.project, s.dryRun)
-	return out
+// Pull executes the equivalent of a `compose pull`
+func (s *ComposeService) Pull(ctx context.Context, options api.PullOptions) (ComposeOutput, error) {
+	c, ctx := s.begin(ctx)
+	defer c.end()
+	if err := c.lockImages(ctx); err != nil {
+		return ComposeOutput{}, err
+	}
+	err := c.service.Pull(ctx, c.project, options)
+	return c.parseOutput(), err
 }
 
 // Create executes the equivalent to a `compose create`
+//
+// The dry run view returns ErrDryRunUnsupported if options.Build is non nil.
 func (s *ComposeService) Create(ctx context.Context, options api.CreateOptions) (ComposeOutput, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	defer s.resetBuf()
-	err := s.service.Create(ctx, s.project, options)
-	return s.parseOutput(), err
+	c, ctx := s.begin(ctx)
+	defer c.end()
+	if err := c.rejectBuild(options.Build); err != nil {
+		return ComposeOutput{}, err
+	}
+	if err := c.lock(ctx, options.Services, true, projectResources); err != nil {
+		return ComposeOutput{}, err
+	}
+	project, err := forServices(c.project, options.Services, true)
+	if err != nil {
+		return ComposeOutput{}, err
+	}
+	err = c.service.Create(ctx, project, options)
+	return c.parseOutput(), err
 }
 
 // Start executes the equivalent to a `compose start`
 func (s *ComposeService) Start(ctx context.Context, options api.StartOptions) (ComposeOutput, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	defer s.resetBuf()
+	c, ctx := s.begin(ctx)
+	defer c.end()
+	if err := c.lock(ctx, options.Services, true); err != nil {
+		return ComposeOutput{}, err
+	}
 	if options.Project == nil {
-		options.Project = s.project
+		project, err := forServices(c.project, options.Services, true)
+		if err != nil {
+			return ComposeOutput{}, err
+		}
+		options.Project = project
 	}
-	err := s.service.Start(ctx, s.projectName, options)
-	return s.parseOutput(), err
+	err := c.service.Start(ctx, s.projectName, options)
+	return c.parseOutput(), err
 }
 
 // Restart restarts containers
 func (s *ComposeService) Restart(ctx context.Context, options api.RestartOptions) (ComposeOutput, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	defer s.resetBuf()
+	c, ctx := s.begin(ctx)
+	defer c.end()
+	if err := c.lock(ctx, options.Services, !options.NoDeps); err != nil {
+		return ComposeOutput{}, err
+	}
 	if options.Project == nil {
-		options.Project = s.project
+		options.Project = c.project
 	}
-	err := s.service.Restart(ctx, s.projectName, options)
-	return s.parseOutput(), err
+	err := c.service.Restart(ctx, s.projectName, options)
+	return c.parseOutput(), err
 }
 
 // Stop executes the equivalent to a `compose stop`
 func (s *ComposeService) Stop(ctx context.Context, options api.StopOptions) (ComposeOutput, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	defer s.resetBuf()
+	c, ctx := s.begin(ctx)
+	defer c.end()
+	if err := c.lock(ctx, options.Services, false); err != nil {
+		return ComposeOutput{}, err
+	}
 	if options.Project == nil {
-		options.Project = s.project
+		options.Project = c.project
 	}
-	err := s.service.Stop(ctx, s.projectName, options)
-	return s.parseOutput(), err
+	err := c.service.Stop(ctx, s.projectName, options)
+	return c.parseOutput(), err
+}
+
+// Up executes the equivalent to a `compose up`
+//
+// options.Start is treated as `compose up` cli does.
+// If options.Start.Services is nil, options.Create.Services is used instead.
+// Non-empty options.Start.ExitCodeFrom implies options.Start.CascadeStop.
+//
+// If options.Start.Wait is true, Up blocks until containers reached the running|healthy state
+// or options.Start.WaitTimeout elapses (only when WaitTimeout is positive).
+// Wait implies detached mode, thus it can not be combined with Attach, CascadeStop nor ExitCodeFrom.
+//
+// If options.Start.Attach is non nil, Up forwards logs to it and blocks until attached containers exit.
+// While blocking, compose installs its own handler for SIGINT and SIGTERM via signal.Notify.
+// Unlike RunOneOffContainer it does not reset handlers installed by user code.
+// If the exit code taken from containers is non-zero, the returned error is cli.StatusError.
+// The dry run view never attaches: Attach, CascadeStop and ExitCodeFrom are ignored
+// and Up returns just after containers would be started.
+// It returns ErrDryRunUnsupported if options.Create.Build is non nil.
+func (s *ComposeService) Up(ctx context.Context, options api.UpOptions) (ComposeOutput, error) {
+	c, ctx := s.begin(ctx)
+	defer c.end()
+	if err := c.rejectBuild(options.Create.Build); err != nil {
+		return ComposeOutput{}, err
+	}
+	if options.Start.Services == nil {
+		options.Start.Services = options.Create.Services
+	}
+	if options.Start.ExitCodeFrom != "" {
+		options.Start.CascadeStop = true
+	}
+	if options.Start.Wait {
+		if options.Start.Attach != nil || options.Start.CascadeStop {
+			return ComposeOutput{}, fmt.Errorf("Start.Wait can not be combined with Attach, CascadeStop nor ExitCodeFrom")
+		}
+	}
+	if options.Start.WaitTimeout < 0 {
+		return ComposeOutput{}, fmt.Errorf("Start.WaitTimeout must not be negative but is %s", options.Start.WaitTimeout)
+	}
+	// The dry run client can not attach, while compose skips attaching only in its own dry run mode.
+	skipAttach := c.dryRun && options.Start.Attach != nil
+	if skipAttach {
+		options.Start.Attach = nil
+		options.Start.CascadeStop = false
+		options.Start.ExitCodeFrom = ""
+	}
+	if err := c.lock(ctx, options.Create.Services, true, projectResources); err != nil {
+		return ComposeOutput{}, err
+	}
+	project, err := forServices(c.project, options.Create.Services, true)
+	if err != nil {
+		return ComposeOutput{}, err
+	}
+	if options.Start.Project == nil {
+		options.Start.Project = project
+	}
+	err = c.service.Up(ctx, project, options)
+	if skipAttach && err == nil {
+		fmt.Fprintln(c.cli.Out(), "end of 'compose up' output, interactive run is not supported in dry-run mode")
+	}
+	return c.parseOutput(), err
 }
 
 // Down executes the equivalent to a `compose down`
 func (s *ComposeService) Down(ctx context.Context, options api.DownOptions) (ComposeOutput, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	defer s.resetBuf()
+	c, ctx := s.begin(ctx)
+	defer c.end()
+	if err := c.lock(ctx, options.Services, false, projectResources); err != nil {
+		return ComposeOutput{}, err
+	}
 	if options.Project == nil {
-		options.Project = s.project
+		options.Project = c.project
 	}
-	err := s.service.Down(ctx, s.projectName, options)
-	return s.parseOutput(), err
+	err := c.service.Down(ctx, s.projectName, options)
+	return c.parseOutput(), err
 }
 
 // Ps executes the equivalent to a `compose ps`
+//
+// Ps does not wait for other calls.
 func (s *ComposeService) Ps(ctx context.Context, options api.PsOptions) ([]api.ContainerSummary, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	c, ctx := s.begin(ctx)
+	defer c.end()
 	if options.Project == nil {
-		options.Project = s.project
+		options.Project = c.project
 	}
-	summary, err := s.service.Ps(ctx, s.projectName, options)
+	summary, err := c.service.Ps(ctx, s.projectName, options)
 	if err != nil {
 		return nil, err
 	}
@@ -164,60 +287,146 @@ func (s *ComposeService) Ps(ctx context.Context, options api.PsOptions) ([]api.C
 
 // Kill executes the equivalent to a `compose kill`
 func (s *ComposeService) Kill(ctx context.Context, options api.KillOptions) (ComposeOutput, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	defer s.resetBuf()
+	c, ctx := s.begin(ctx)
+	defer c.end()
+	var extra []string
+	if options.RemoveOrphans {
+		extra = append(extra, projectResources)
+	}
+	if err := c.lock(ctx, options.Services, false, extra...); err != nil {
+		return ComposeOutput{}, err
+	}
 	if options.Project == nil {
-		options.Project = s.project
+		options.Project = c.project
 	}
-	err := s.service.Kill(ctx, s.projectName, options)
-	return s.parseOutput(), err
+	err := c.service.Kill(ctx, s.projectName, options)
+	return c.parseOutput(), err
+}
+
+// Pause executes the equivalent to a `compose pause`
+func (s *ComposeService) Pause(ctx context.Context, options api.PauseOptions) (ComposeOutput, error) {
+	c, ctx := s.begin(ctx)
+	defer c.end()
+	if err := c.lock(ctx, options.Services, false); err != nil {
+		return ComposeOutput{}, err
+	}
+	if options.Project == nil {
+		options.Project = c.project
+	}
+	err := c.service.Pause(ctx, s.projectName, options)
+	return c.parseOutput(), err
+}
+
+// UnPause executes the equivalent to a `compose unpause`
+func (s *ComposeService) UnPause(ctx context.Context, options api.PauseOptions) (ComposeOutput, error) {
+	c, ctx := s.begin(ctx)
+	defer c.end()
+	if err := c.lock(ctx, options.Services, false); err != nil {
+		return ComposeOutput{}, err
+	}
+	if options.Project == nil {
+		options.Project = c.project
+	}
+	err := c.service.UnPause(ctx, s.projectName, options)
+	return c.parseOutput(), err
 }
 
 // RunOneOffContainer is not exposed here since it calls `signal.Reset` on invocation,
 // which removes all signal handlers installed by user code.
 // Since it destroys our signal handling planning, we will not be able to rely on it.
+// Run is provided instead.
 
 // Remove executes the equivalent to a `compose rm`
 func (s *ComposeService) Remove(ctx context.Context, options api.RemoveOptions) (ComposeOutput, error) {
+	c, ctx := s.begin(ctx)
+	defer c.end()
+	if err := c.lock(ctx, options.Services, false); err != nil {
+		return ComposeOutput{}, err
+	}
+	if options.Project == nil {
+		options.Project = c.project
+	}
+	err := c.service.Remove(ctx, s.projectName, options)
+	return c.parseOutput(), err
+}
+
+// DryRun returns a view of s which runs every call in dry run mode.
+// Calls to the view report what they would do in returned ComposeOutput without changing anything,
+// while s is kept in normal mode. The view is created once and is reused by later calls.
+//
+// The view shares the wrapped project with s. Changes made to it by calls to s are visible to the view.
+// Calls to the view never wait for other calls, since they change nothing.
+// Calling DryRun on the view returns the view itself.
+//
+// The view runs compose against the dry run docker api client, but not in compose's own dry run mode,
+// which would write output to the process' stdout and stderr instead of the returned ComposeOutput.
+// Thus calls which compose can not simulate through the client are refused with ErrDryRunUnsupported,
+// i.e. Build, and Create, Up and Run with build options, since buildkit talks to the daemon directly.
+// Output of some calls also differs from `compose --dry-run`:
+// Remove with Stop does not report running containers as removed,
+// and Copy to containers reads and archives sources on the host before the client discards them.
+func (s *ComposeService) DryRun() (*ComposeService, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	defer s.resetBuf()
-	if options.Project == nil {
-		options.Project = s.project
+	if s.dryRun {
+		return s, nil
 	}
-	err := s.service.Remove(ctx, s.projectName, options)
-	return s.parseOutput(), err
+	if s.dryRunView != nil {
+		return s.dryRunView, nil
+	}
+
+	cli, err := newDryRunCli(s.cli)
+	if err != nil {
+		return nil, err
+	}
+	s.dryRunView = &ComposeService{
+		dryRun:      true,
+		cli:         cli,
+		projectMu:   s.projectMu,
+		locks:       s.locks,
+		projectName: s.projectName,
+		project:     s.project,
+		newService:  s.newService,
+	}
+	return s.dryRunView, nil
 }
 
 // DryRunMode switches c to dry run mode if dryRun is true.
 // Implementations might not change back to normal mode even if dryRun is false.
 // User must call this only once and only when the user whishes to use dry run client.
+//
+// Deprecated: use DryRun, which leaves s in normal mode.
 func (s *ComposeService) DryRunMode(ctx context.Context, dryRun bool) (context.Context, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if dryRun {
-		cli, err := command.NewDockerCli()
-		if err != nil {
-			return ctx, err
-		}
-
-		options := flags.NewClientOptions()
-		options.Context = s.cli.CurrentContext()
-		err = cli.Initialize(
-			options,
-			command.WithInitializeClient(func(cli *command.DockerCli) (client.APIClient, error) {
-				return api.NewDryRunClient(s.cli.Client(), s.cli)
-			}),
-		)
+		cli, err := newDryRunCli(s.cli)
 		if err != nil {
 			return ctx, err
 		}
-
 		s.dryRun = true
 		s.cli = cli
-		s.overrideOutputStreams()
-		s.service = api.NewServiceProxy().WithService(compose.NewComposeService(s.cli))
 	}
 	return context.WithValue(ctx, api.DryRunKey{}, dryRun), nil
 }
+
+// newDryRunCli returns a docker cli whose client is the dry run client wrapping one of dockerCli.
+func newDryRunCli(dockerCli command.Cli) (command.Cli, error) {
+	cli, err := command.NewDockerCli()
+	if err != nil {
+		return nil, err
+	}
+
+	options := flags.NewClientOptions()
+	options.Context = dockerCli.CurrentContext()
+	err = cli.Initialize(
+		options,
+		command.WithInitializeClient(func(cli *command.DockerCli) (client.APIClient, error) {
+			return api.NewDryRunClient(dockerCli.Client(), dockerCli)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return cli, nil
+}
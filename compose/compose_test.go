@@ -3,7 +3,10 @@ package compose
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/compose-spec/compose-go/types"
+	"github.com/docker/cli/cli/command"
 	"github.com/docker/compose/v2/pkg/api"
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/require"
@@ -14,10 +17,10 @@ func TestComposeService_dind(t *testing.T) {
 	composeService, err := loaderAdditional.LoadComposeService(context.Background())
 	require.NoError(err)
 
-	dryRunCtx, err := composeService.DryRunMode(context.Background(), true)
+	dryRun, err := composeService.DryRun()
 	require.NoError(err)
 
-	out, err := composeService.Create(dryRunCtx, api.CreateOptions{})
+	out, err := dryRun.Create(context.Background(), api.CreateOptions{})
 	require.NoError(err)
 
 	delete(out.Resource, "Network:default")
@@ -25,3 +28,114 @@ func TestComposeService_dind(t *testing.T) {
 		t.Errorf("not equal. diff =%s", diff)
 	}
 }
+
+func TestComposeService_DryRun_view(t *testing.T) {
+	require := require.New(t)
+	composeService, err := loaderAdditional.LoadComposeService(context.Background())
+	require.NoError(err)
+
+	dryRun, err := composeService.DryRun()
+	require.NoError(err)
+	require.True(dryRun.dryRun)
+	require.False(composeService.dryRun)
+	require.NotSame(composeService.cli, dryRun.cli)
+	require.Same(composeService.project, dryRun.project)
+	require.Same(composeService.projectMu, dryRun.projectMu)
+	require.Same(composeService.locks, dryRun.locks)
+
+	again, err := composeService.DryRun()
+	require.NoError(err)
+	require.Same(dryRun, again)
+	self, err := dryRun.DryRun()
+	require.NoError(err)
+	require.Same(dryRun, self)
+
+	c, ctx := dryRun.begin(context.Background())
+	c.end()
+	require.True(c.dryRun)
+	require.Equal(true, ctx.Value(api.DryRunKey{}))
+	c, ctx = composeService.begin(context.Background())
+	c.end()
+	require.False(c.dryRun)
+	require.Nil(ctx.Value(api.DryRunKey{}))
+}
+
+func TestComposeService_Up_invalid_options(t *testing.T) {
+	require := require.New(t)
+	composeService, err := loaderAdditional.LoadComposeService(context.Background())
+	require.NoError(err)
+
+	for _, opt := range []api.UpOptions{
+		{Start: api.StartOptions{Wait: true, Attach: nopLogConsumer{}}},
+		{Start: api.StartOptions{Wait: true, CascadeStop: true}},
+		{Start: api.StartOptions{Wait: true, ExitCodeFrom: "sample_service"}},
+		{Start: api.StartOptions{WaitTimeout: -1}},
+	} {
+		_, err := composeService.Up(context.Background(), opt)
+		require.Error(err)
+	}
+}
+
+type nopLogConsumer struct{}
+
+func (nopLogConsumer) Log(containerName, message string) {}
+func (nopLogConsumer) Err(containerName, message string) {}
+func (nopLogConsumer) Status(container, msg string)      {}
+func (nopLogConsumer) Register(container string)         {}
+
+func TestComposeService_DryRun_build(t *testing.T) {
+	require := require.New(t)
+	composeService, err := loaderAdditional.LoadComposeService(context.Background())
+	require.NoError(err)
+	dryRun, err := composeService.DryRun()
+	require.NoError(err)
+
+	// The view must refuse before compose reaches buildkit, which is not covered by the dry run client.
+	_, err = dryRun.Build(context.Background(), api.BuildOptions{})
+	require.ErrorIs(err, ErrDryRunUnsupported)
+	_, err = dryRun.Create(context.Background(), api.CreateOptions{Build: &api.BuildOptions{}})
+	require.ErrorIs(err, ErrDryRunUnsupported)
+	_, err = dryRun.Up(context.Background(), api.UpOptions{Create: api.CreateOptions{Build: &api.BuildOptions{}}})
+	require.ErrorIs(err, ErrDryRunUnsupported)
+	_, err = dryRun.Run(context.Background(), api.RunOptions{Service: "sample_service", Build: &api.BuildOptions{}})
+	require.ErrorIs(err, ErrDryRunUnsupported)
+}
+
+func TestComposeService_disjoint_services(t *testing.T) {
+	require := require.New(t)
+	composeService := NewComposeService("example_compose", loadFromString(waitComposeYaml), nil)
+
+	stopping := make(chan struct{})
+	release := make(chan struct{})
+	created := make(chan []string, 1)
+	composeService.newService = func(command.Cli) api.Service {
+		return &api.ServiceProxy{
+			StopFn: func(ctx context.Context, projectName string, options api.StopOptions) error {
+				close(stopping)
+				<-release
+				return nil
+			},
+			CreateFn: func(ctx context.Context, project *types.Project, options api.CreateOptions) error {
+				created <- project.ServiceNames()
+				return nil
+			},
+		}
+	}
+
+	stopped := make(chan error, 1)
+	go func() {
+		_, err := composeService.Stop(context.Background(), api.StopOptions{Services: []string{"worker"}})
+		stopped <- err
+	}()
+	<-stopping
+
+	// Create does not wait for Stop holding worker, thus it must not hand worker to compose.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := composeService.Create(ctx, api.CreateOptions{Services: []string{"app"}})
+	require.NoError(err)
+	require.ElementsMatch([]string{"app", "db", "migrate", "cache"}, <-created)
+
+	close(release)
+	require.NoError(<-stopped)
+}
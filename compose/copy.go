@@ -0,0 +1,218 @@
+package compose
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/docker/compose/v2/pkg/api"
+	dockertypes "github.com/docker/docker/api/types"
+)
+
+// CopyDirection is the direction of ComposeService.Copy.
+type CopyDirection int
+
+const (
+	// CopyIn copies files from the host into containers.
+	CopyIn CopyDirection = iota
+	// CopyOut copies files from a container to the host.
+	CopyOut
+)
+
+// CopyOptions is options for ComposeService.Copy.
+//
+// Exactly one of HostPath, In and Out must be set, matching Direction:
+// HostPath for either direction, In for CopyIn and Out for CopyOut.
+type CopyOptions struct {
+	Direction CopyDirection
+	Service   string
+	// Index selects the replica.
+	// If it is 0, files are copied into all replicas, or out of the replica with the smallest number.
+	Index int
+	// ContainerPath is the path in containers.
+	// If In is set, it must be an existing directory where the tar stream is extracted.
+	ContainerPath string
+	// HostPath is the path on the host.
+	HostPath string
+	// In is a tar stream copied into containers.
+	// It is read entirely into memory before copying so that it can be copied into every replica.
+	In io.Reader
+	// Out receives a tar stream of ContainerPath.
+	Out        io.Writer
+	FollowLink bool
+	CopyUIDGID bool
+}
+
+// CopyResult is the result of copying to or from a container.
+type CopyResult struct {
+	Service   string
+	Num       int
+	Container string
+	// Err is nil if the copy succeeded.
+	Err error
+}
+
+// Copy executes the equivalent to a `compose cp`
+//
+// Copy copies files for each targeted replica in turn, and reports results per container.
+// The returned error is the joined errors of the results,
+// or an error which prevented Copy from starting, e.g. invalid options or no container found.
+//
+// Copying between a host path and containers is delegated to compose,
+// and waits for other calls mutating options.Service.
+// Tar streams are copied through the docker client directly without waiting for other calls.
+func (s *ComposeService) Copy(ctx context.Context, options CopyOptions) ([]CopyResult, error) {
+	if err := validateCopyOptions(options); err != nil {
+		return nil, err
+	}
+	if options.HostPath != "" {
+		return s.copyHostPath(ctx, options)
+	}
+	return s.copyStream(ctx, options)
+}
+
+func validateCopyOptions(options CopyOptions) error {
+	if options.Service == "" || options.ContainerPath == "" {
+		return fmt.Errorf("Service and ContainerPath must not be empty")
+	}
+	set := 0
+	for _, ok := range []bool{options.HostPath != "", options.In != nil, options.Out != nil} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("exactly one of HostPath, In and Out must be set")
+	}
+	switch options.Direction {
+	case CopyIn:
+		if options.Out != nil {
+			return fmt.Errorf("Out can not be used with CopyIn")
+		}
+	case CopyOut:
+		if options.In != nil {
+			return fmt.Errorf("In can not be used with CopyOut")
+		}
+	default:
+		return fmt.Errorf("unknown direction: %d", options.Direction)
+	}
+	return nil
+}
+
+func (c *call) copyTargets(ctx context.Context, options CopyOptions) ([]api.ContainerSummary, error) {
+	containers, err := c.listReplicas(ctx, options.Service, options.Index, true)
+	if err != nil {
+		return nil, err
+	}
+	if options.Direction == CopyOut {
+		// copying from multiple containers of a service doesn't make sense.
+		containers = containers[:1]
+	}
+	return containers, nil
+}
+
+func (s *ComposeService) copyHostPath(ctx context.Context, options CopyOptions) ([]CopyResult, error) {
+	c, ctx := s.begin(ctx)
+	defer c.end()
+	if err := c.lock(ctx, []string{options.Service}, false); err != nil {
+		return nil, err
+	}
+
+	containers, err := c.copyTargets(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]CopyResult, len(containers))
+	for i, container := range containers {
+		num := replicaNumber(container)
+		copyOptions := api.CopyOptions{
+			// Index pins the copy to this container.
+			Index:      num,
+			FollowLink: options.FollowLink,
+			CopyUIDGID: options.CopyUIDGID,
+		}
+		servicePath := options.Service + ":" + options.ContainerPath
+		if options.Direction == CopyIn {
+			copyOptions.Source, copyOptions.Destination = options.HostPath, servicePath
+		} else {
+			copyOptions.Source, copyOptions.Destination = servicePath, options.HostPath
+		}
+		results[i] = CopyResult{
+			Service:   options.Service,
+			Num:       num,
+			Container: container.Name,
+			Err:       c.service.Copy(ctx, s.projectName, copyOptions),
+		}
+	}
+	return results, joinCopyErrors(results)
+}
+
+type copyClient interface {
+	CopyToContainer(ctx context.Context, container, path string, content io.Reader, options dockertypes.CopyToContainerOptions) error
+	CopyFromContainer(ctx context.Context, container, srcPath string) (io.ReadCloser, dockertypes.ContainerPathStat, error)
+}
+
+func (s *ComposeService) copyStream(ctx context.Context, options CopyOptions) ([]CopyResult, error) {
+	c, ctx := s.begin(ctx)
+	defer c.end()
+	containers, err := c.copyTargets(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	return copyContainerStreams(ctx, c.cli.Client(), containers, options)
+}
+
+func copyContainerStreams(ctx context.Context, apiClient copyClient, containers []api.ContainerSummary, options CopyOptions) ([]CopyResult, error) {
+	var content []byte
+	if options.In != nil {
+		var err error
+		content, err = io.ReadAll(options.In)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]CopyResult, len(containers))
+	for i, container := range containers {
+		results[i] = CopyResult{
+			Service:   options.Service,
+			Num:       replicaNumber(container),
+			Container: container.Name,
+		}
+		if options.In != nil {
+			results[i].Err = apiClient.CopyToContainer(
+				ctx,
+				container.ID,
+				options.ContainerPath,
+				bytes.NewReader(content),
+				dockertypes.CopyToContainerOptions{CopyUIDGID: options.CopyUIDGID},
+			)
+			continue
+		}
+		results[i].Err = copyFromContainer(ctx, apiClient, container.ID, options.ContainerPath, options.Out)
+	}
+	return results, joinCopyErrors(results)
+}
+
+func copyFromContainer(ctx context.Context, apiClient copyClient, containerID, path string, w io.Writer) error {
+	r, _, err := apiClient.CopyFromContainer(ctx, containerID, path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	_, err = io.Copy(w, r)
+	return err
+}
+
+func joinCopyErrors(results []CopyResult) error {
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.Container, r.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
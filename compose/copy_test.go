@@ -0,0 +1,90 @@
+package compose
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/docker/compose/v2/pkg/api"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeCopyClient struct {
+	copied map[string]string
+	failOn string
+}
+
+func (c *fakeCopyClient) CopyToContainer(ctx context.Context, container, path string, content io.Reader, options dockertypes.CopyToContainerOptions) error {
+	if container == c.failOn {
+		return errors.New("no such directory")
+	}
+	b, _ := io.ReadAll(content)
+	c.copied[container+":"+path] = string(b)
+	return nil
+}
+
+func (c *fakeCopyClient) CopyFromContainer(ctx context.Context, container, srcPath string) (io.ReadCloser, dockertypes.ContainerPathStat, error) {
+	return io.NopCloser(strings.NewReader("tar of " + container + ":" + srcPath)), dockertypes.ContainerPathStat{}, nil
+}
+
+func TestCopyContainerStreams(t *testing.T) {
+	assert := assert.New(t)
+
+	containers := []api.ContainerSummary{
+		{ID: "id1", Name: "example_compose-web-1", Labels: map[string]string{api.ContainerNumberLabel: "1"}},
+		{ID: "id2", Name: "example_compose-web-2", Labels: map[string]string{api.ContainerNumberLabel: "2"}},
+	}
+
+	client := &fakeCopyClient{copied: map[string]string{}, failOn: "id2"}
+	results, err := copyContainerStreams(context.Background(), client, containers, CopyOptions{
+		Direction:     CopyIn,
+		Service:       "web",
+		ContainerPath: "/etc/app",
+		In:            strings.NewReader("tar"),
+	})
+	assert.ErrorContains(err, "example_compose-web-2: no such directory")
+	assert.Equal(map[string]string{"id1:/etc/app": "tar"}, client.copied)
+	assert.Len(results, 2)
+	assert.NoError(results[0].Err)
+	assert.Equal(1, results[0].Num)
+	assert.Error(results[1].Err)
+	assert.Equal(2, results[1].Num)
+
+	var out bytes.Buffer
+	results, err = copyContainerStreams(context.Background(), client, containers[:1], CopyOptions{
+		Direction:     CopyOut,
+		Service:       "web",
+		ContainerPath: "/var/crash",
+		Out:           &out,
+	})
+	assert.NoError(err)
+	assert.Len(results, 1)
+	assert.Equal("tar of id1:/var/crash", out.String())
+}
+
+func TestValidateCopyOptions(t *testing.T) {
+	for _, tc := range []struct {
+		options CopyOptions
+		valid   bool
+	}{
+		{CopyOptions{Direction: CopyIn, Service: "web", ContainerPath: "/", HostPath: "./a"}, true},
+		{CopyOptions{Direction: CopyOut, Service: "web", ContainerPath: "/", Out: io.Discard}, true},
+		{CopyOptions{Direction: CopyIn, Service: "web", ContainerPath: "/", Out: io.Discard}, false},
+		{CopyOptions{Direction: CopyOut, Service: "web", ContainerPath: "/", In: strings.NewReader("")}, false},
+		{CopyOptions{Direction: CopyIn, Service: "web", ContainerPath: "/", HostPath: "./a", In: strings.NewReader("")}, false},
+		{CopyOptions{Direction: CopyIn, Service: "web", ContainerPath: "/"}, false},
+		{CopyOptions{Direction: CopyIn, ContainerPath: "/", HostPath: "./a"}, false},
+		{CopyOptions{Direction: 5, Service: "web", ContainerPath: "/", HostPath: "./a"}, false},
+	} {
+		err := validateCopyOptions(tc.options)
+		if tc.valid {
+			assert.NoError(t, err, "%+v", tc.options)
+		} else {
+			assert.Error(t, err, "%+v", tc.options)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package compose
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/docker/compose/v2/pkg/api"
+)
+
+// Some of container event actions.
+// See https://docs.docker.com/engine/reference/commandline/events/ for the full list.
+const (
+	ActionCreate       = "create"
+	ActionStart        = "start"
+	ActionRestart      = "restart"
+	ActionStop         = "stop"
+	ActionKill         = "kill"
+	ActionDie          = "die"
+	ActionOom          = "oom"
+	ActionDestroy      = "destroy"
+	ActionPause        = "pause"
+	ActionUnpause      = "unpause"
+	ActionHealthStatus = "health_status"
+)
+
+// Event is a container event of the project.
+type Event struct {
+	Timestamp time.Time
+	Service   string
+	// Num is the replica number of the container. It is zero if it is unknown.
+	Num         int
+	Container   string
+	ContainerID string
+	// Action is the action of the event, e.g. ActionDie.
+	// Actions of exec events have the command appended as docker reports, e.g. "exec_start: sh -c date".
+	Action string
+	// Health is the health status reported by ActionHealthStatus event, e.g. "healthy".
+	Health string
+	// ExitCode is the exit code reported by ActionDie event.
+	ExitCode int
+	// Attributes is attributes of the event, except for labels added by compose.
+	Attributes map[string]string
+}
+
+// Events executes the equivalent to a `compose events`
+//
+// Events calls consumer with container events of the project until ctx is cancelled or consumer returns an error.
+// Events of one-off containers are ignored.
+// If services is non empty, events are limited to those services.
+//
+// Events does not wait for other calls.
+func (s *ComposeService) Events(ctx context.Context, services []string, consumer func(event Event) error) error {
+	c, ctx := s.begin(ctx)
+	defer c.end()
+	projectName, project := c.projectName, c.project
+
+	return c.service.Events(ctx, projectName, api.EventsOptions{
+		Services: services,
+		Consumer: func(event api.Event) error {
+			return consumer(toEvent(event, projectName, project))
+		},
+	})
+}
+
+func toEvent(event api.Event, projectName string, project *types.Project) Event {
+	converted := Event{
+		Timestamp:   event.Timestamp,
+		Service:     event.Service,
+		Container:   event.Attributes["name"],
+		ContainerID: event.Container,
+		Action:      event.Status,
+		Attributes:  event.Attributes,
+	}
+
+	if service, num, ok := resolveContainerName(converted.Container, projectName, project); ok {
+		converted.Service = service
+		converted.Num = num
+	}
+
+	// health_status events are reported as "health_status: healthy".
+	// Other actions may contain ":" as well, e.g. "exec_start: sh -c date", and are left as is.
+	if health, found := strings.CutPrefix(event.Status, ActionHealthStatus+":"); found {
+		converted.Action = ActionHealthStatus
+		converted.Health = strings.TrimSpace(health)
+	}
+	if converted.Action == ActionDie {
+		converted.ExitCode, _ = strconv.Atoi(event.Attributes["exitCode"])
+	}
+	return converted
+}
@@ -0,0 +1,80 @@
+package compose
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestToEvent(t *testing.T) {
+	project := loadFromString(prefixedServiceNameComposeYaml)
+	now := time.Now()
+
+	for _, tc := range []struct {
+		input    api.Event
+		expected Event
+	}{
+		{
+			input: api.Event{
+				Timestamp:  now,
+				Service:    "web-admin",
+				Container:  "0123456789ab",
+				Status:     "die",
+				Attributes: map[string]string{"name": "example_compose-web-admin-2", "exitCode": "137"},
+			},
+			expected: Event{
+				Timestamp:   now,
+				Service:     "web-admin",
+				Num:         2,
+				Container:   "example_compose-web-admin-2",
+				ContainerID: "0123456789ab",
+				Action:      ActionDie,
+				ExitCode:    137,
+				Attributes:  map[string]string{"name": "example_compose-web-admin-2", "exitCode": "137"},
+			},
+		},
+		{
+			input: api.Event{
+				Timestamp:  now,
+				Service:    "named",
+				Container:  "0123456789ab",
+				Status:     "health_status: unhealthy",
+				Attributes: map[string]string{"name": "custom-name"},
+			},
+			expected: Event{
+				Timestamp:   now,
+				Service:     "named",
+				Num:         1,
+				Container:   "custom-name",
+				ContainerID: "0123456789ab",
+				Action:      ActionHealthStatus,
+				Health:      "unhealthy",
+				Attributes:  map[string]string{"name": "custom-name"},
+			},
+		},
+		{
+			input: api.Event{
+				Timestamp:  now,
+				Service:    "named",
+				Container:  "0123456789ab",
+				Status:     "exec_start: sh -c date",
+				Attributes: map[string]string{"name": "custom-name", "execID": "fedcba987654"},
+			},
+			expected: Event{
+				Timestamp:   now,
+				Service:     "named",
+				Num:         1,
+				Container:   "custom-name",
+				ContainerID: "0123456789ab",
+				Action:      "exec_start: sh -c date",
+				Attributes:  map[string]string{"name": "custom-name", "execID": "fedcba987654"},
+			},
+		},
+	} {
+		if diff := cmp.Diff(tc.expected, toEvent(tc.input, "example_compose", project)); diff != "" {
+			t.Errorf("not equal. diff = %s", diff)
+		}
+	}
+}
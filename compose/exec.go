@@ -0,0 +1,219 @@
+package compose
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/docker/compose/v2/pkg/api"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// ExecResult is the result of ComposeService.Exec.
+type ExecResult struct {
+	ContainerID   string
+	ContainerName string
+	// ExitCode is the exit code of the executed command. It is always 0 if detached.
+	ExitCode int
+	// Stdout and Stderr are outputs of the executed command.
+	// Both are empty if detached. Stderr is also empty if Tty is true, since the tty merges stderr into stdout.
+	Stdout, Stderr string
+}
+
+// Exec executes the equivalent to a `compose exec`
+//
+// Exec runs options.Command in the running container of options.Service.
+// options.Index selects the replica. If it is 0, the replica with the smallest number is used.
+// One-off containers are never selected.
+//
+// If stdin is non nil, it is piped to the command and closed for writing when stdin reaches EOF.
+// options.Interactive is ignored.
+// options.Environment is resolved against the environment of the project, as Run does.
+//
+// Unlike Exec of compose, Exec never touches process-wide signal handling and the terminal.
+// If ctx is cancelled while the command is running, Exec returns ctx.Err() without waiting for it,
+// since the docker engine provides no way to stop an exec process.
+//
+// In dry run mode, Exec returns just after the target container is found.
+//
+// Exec does not wait for other calls.
+func (s *ComposeService) Exec(ctx context.Context, stdin io.Reader, options api.RunOptions) (ExecResult, error) {
+	if len(options.Command) == 0 {
+		return ExecResult{}, fmt.Errorf("Command must not be empty")
+	}
+	if options.Detach && stdin != nil {
+		return ExecResult{}, fmt.Errorf("stdin can not be piped in detached mode")
+	}
+
+	c, ctx := s.begin(ctx)
+	defer c.end()
+	target, err := c.execTarget(ctx, options)
+	apiClient := c.cli.Client()
+	dryRun := c.dryRun
+	env := resolveRunEnvironment(c.project, options.Environment)
+
+	result := ExecResult{ContainerID: target.ID, ContainerName: target.Name}
+	if err != nil || dryRun {
+		return result, err
+	}
+
+	config := dockertypes.ExecConfig{
+		User:         options.User,
+		Privileged:   options.Privileged,
+		Tty:          options.Tty,
+		AttachStdin:  stdin != nil,
+		AttachStdout: !options.Detach,
+		AttachStderr: !options.Detach,
+		Detach:       options.Detach,
+		Env:          env,
+		WorkingDir:   options.WorkingDir,
+		Cmd:          options.Command,
+	}
+	result.ExitCode, result.Stdout, result.Stderr, err = runExec(ctx, apiClient, target.ID, stdin, config)
+	return result, err
+}
+
+func (c *call) execTarget(ctx context.Context, options api.RunOptions) (api.ContainerSummary, error) {
+	containers, err := c.listReplicas(ctx, options.Service, options.Index, false)
+	if err != nil {
+		return api.ContainerSummary{}, err
+	}
+	return containers[0], nil
+}
+
+// listReplicas lists containers of service, excluding one-off containers, sorted by the replica number.
+// If index is positive, only the replica numbered index is listed.
+// Stopped containers are listed only if all is true.
+// It returns an error if no container is found.
+func (c *call) listReplicas(ctx context.Context, service string, index int, all bool) ([]api.ContainerSummary, error) {
+	containers, err := c.service.Ps(ctx, c.projectName, api.PsOptions{
+		Project:  c.project,
+		All:      all,
+		Services: []string{service},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	containers = slices.DeleteFunc(containers, func(c api.ContainerSummary) bool {
+		if c.Labels[api.OneoffLabel] == "True" {
+			return true
+		}
+		if index > 0 {
+			return c.Labels[api.ContainerNumberLabel] != strconv.Itoa(index)
+		}
+		return false
+	})
+	if len(containers) == 0 {
+		switch {
+		case all && index > 0:
+			return nil, fmt.Errorf("service %q has no container #%d", service, index)
+		case all:
+			return nil, fmt.Errorf("no container found for service %q", service)
+		case index > 0:
+			return nil, fmt.Errorf("service %q is not running container #%d", service, index)
+		default:
+			return nil, fmt.Errorf("service %q is not running", service)
+		}
+	}
+
+	slices.SortFunc(containers, func(i, j api.ContainerSummary) int {
+		return replicaNumber(i) - replicaNumber(j)
+	})
+	return containers, nil
+}
+
+func replicaNumber(c api.ContainerSummary) int {
+	num, _ := strconv.Atoi(c.Labels[api.ContainerNumberLabel])
+	return num
+}
+
+// resolveRunEnvironment resolves variables without value, e.g. "FOO", against the environment of project.
+// Variables which are not found are removed.
+func resolveRunEnvironment(project *types.Project, environment []string) []string {
+	if len(environment) == 0 {
+		return nil
+	}
+	resolved := types.NewMappingWithEquals(environment).
+		Resolve(project.Environment.Resolve).
+		RemoveEmpty()
+	env := make([]string, 0, len(resolved))
+	for k, v := range resolved {
+		env = append(env, k+"="+*v)
+	}
+	slices.Sort(env)
+	return env
+}
+
+type execClient interface {
+	ContainerExecCreate(ctx context.Context, container string, config dockertypes.ExecConfig) (dockertypes.IDResponse, error)
+	ContainerExecStart(ctx context.Context, execID string, config dockertypes.ExecStartCheck) error
+	ContainerExecAttach(ctx context.Context, execID string, config dockertypes.ExecStartCheck) (dockertypes.HijackedResponse, error)
+	ContainerExecInspect(ctx context.Context, execID string) (dockertypes.ContainerExecInspect, error)
+}
+
+func runExec(
+	ctx context.Context,
+	apiClient execClient,
+	containerID string,
+	stdin io.Reader,
+	config dockertypes.ExecConfig,
+) (exitCode int, stdout, stderr string, err error) {
+	created, err := apiClient.ContainerExecCreate(ctx, containerID, config)
+	if err != nil {
+		return 0, "", "", err
+	}
+
+	startCheck := dockertypes.ExecStartCheck{Detach: config.Detach, Tty: config.Tty}
+	if config.Detach {
+		return 0, "", "", apiClient.ContainerExecStart(ctx, created.ID, startCheck)
+	}
+
+	resp, err := apiClient.ContainerExecAttach(ctx, created.ID, startCheck)
+	if err != nil {
+		return 0, "", "", err
+	}
+	defer resp.Close()
+
+	if stdin != nil {
+		go func() {
+			// Errors are ignored as docker cli does. The command sees EOF in either case.
+			_, _ = io.Copy(resp.Conn, stdin)
+			_ = resp.CloseWrite()
+		}()
+	}
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	copyDone := make(chan error, 1)
+	go func() {
+		var err error
+		if config.Tty {
+			_, err = io.Copy(&stdoutBuf, resp.Reader)
+		} else {
+			_, err = stdcopy.StdCopy(&stdoutBuf, &stderrBuf, resp.Reader)
+		}
+		copyDone <- err
+	}()
+
+	select {
+	case <-ctx.Done():
+		resp.Close()
+		<-copyDone
+		return 0, stdoutBuf.String(), stderrBuf.String(), ctx.Err()
+	case err = <-copyDone:
+	}
+	if err != nil {
+		return 0, stdoutBuf.String(), stderrBuf.String(), err
+	}
+
+	inspected, err := apiClient.ContainerExecInspect(ctx, created.ID)
+	if err != nil {
+		return 0, stdoutBuf.String(), stderrBuf.String(), err
+	}
+	return inspected.ExitCode, stdoutBuf.String(), stderrBuf.String(), nil
+}
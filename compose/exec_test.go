@@ -0,0 +1,114 @@
+package compose
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeConn records bytes written. Other methods of net.Conn are not implemented.
+type fakeConn struct {
+	net.Conn
+	mu          sync.Mutex
+	written     bytes.Buffer
+	writeClosed chan struct{}
+}
+
+func (c *fakeConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.written.Write(p)
+}
+
+func (c *fakeConn) CloseWrite() error {
+	close(c.writeClosed)
+	return nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+type fakeExecClient struct {
+	conn    *fakeConn
+	output  []byte
+	config  dockertypes.ExecConfig
+	started bool
+}
+
+func (c *fakeExecClient) ContainerExecCreate(ctx context.Context, container string, config dockertypes.ExecConfig) (dockertypes.IDResponse, error) {
+	c.config = config
+	return dockertypes.IDResponse{ID: "exec"}, nil
+}
+
+func (c *fakeExecClient) ContainerExecStart(ctx context.Context, execID string, config dockertypes.ExecStartCheck) error {
+	c.started = true
+	return nil
+}
+
+func (c *fakeExecClient) ContainerExecAttach(ctx context.Context, execID string, config dockertypes.ExecStartCheck) (dockertypes.HijackedResponse, error) {
+	c.conn = &fakeConn{writeClosed: make(chan struct{})}
+	return dockertypes.HijackedResponse{
+		Conn:   c.conn,
+		Reader: bufio.NewReader(bytes.NewReader(c.output)),
+	}, nil
+}
+
+func (c *fakeExecClient) ContainerExecInspect(ctx context.Context, execID string) (dockertypes.ContainerExecInspect, error) {
+	return dockertypes.ContainerExecInspect{ExecID: execID, ExitCode: 3}, nil
+}
+
+func TestRunExec(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	_, _ = stdcopy.NewStdWriter(&buf, stdcopy.Stdout).Write([]byte("out\n"))
+	_, _ = stdcopy.NewStdWriter(&buf, stdcopy.Stderr).Write([]byte("err\n"))
+
+	client := &fakeExecClient{output: buf.Bytes()}
+	exitCode, stdout, stderr, err := runExec(
+		context.Background(),
+		client,
+		"id",
+		bytes.NewReader([]byte("input")),
+		dockertypes.ExecConfig{AttachStdin: true, Cmd: []string{"cat"}},
+	)
+	assert.NoError(err)
+	assert.Equal(3, exitCode)
+	assert.Equal("out\n", stdout)
+	assert.Equal("err\n", stderr)
+
+	<-client.conn.writeClosed
+	client.conn.mu.Lock()
+	assert.Equal("input", client.conn.written.String())
+	client.conn.mu.Unlock()
+
+	client = &fakeExecClient{output: []byte("merged\n")}
+	_, stdout, stderr, err = runExec(context.Background(), client, "id", nil, dockertypes.ExecConfig{Tty: true})
+	assert.NoError(err)
+	assert.Equal("merged\n", stdout)
+	assert.Equal("", stderr)
+
+	client = &fakeExecClient{}
+	_, _, _, err = runExec(context.Background(), client, "id", nil, dockertypes.ExecConfig{Detach: true})
+	assert.NoError(err)
+	assert.True(client.started)
+	assert.Nil(client.conn)
+}
+
+func TestResolveRunEnvironment(t *testing.T) {
+	project := loadFromString(prefixedServiceNameComposeYaml)
+	project.Environment["COMPOSE_WRAPPER_SET"] = "set"
+	assert.Equal(
+		t,
+		[]string{"COMPOSE_WRAPPER_SET=set", "FOO=foo"},
+		resolveRunEnvironment(project, []string{"FOO=foo", "COMPOSE_WRAPPER_SET", "COMPOSE_WRAPPER_UNSET"}),
+	)
+}
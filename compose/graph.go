@@ -0,0 +1,302 @@
+package compose
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/compose-spec/compose-go/types"
+)
+
+// DependencyKind is how a service refers to another.
+type DependencyKind string
+
+const (
+	DependencyDependsOn   DependencyKind = "depends_on"
+	DependencyLinks       DependencyKind = "links"
+	DependencyNetworkMode DependencyKind = "network_mode"
+	// DependencyNamespace is ipc, pid, uts or cgroup set to service:name.
+	DependencyNamespace   DependencyKind = "namespace"
+	DependencyVolumesFrom DependencyKind = "volumes_from"
+)
+
+// DependencyEdge is a dependency of service From on service To.
+type DependencyEdge struct {
+	From string
+	To   string
+	// Kinds are how From refers to To, sorted.
+	// Projects normalized by the loader also have depends_on for links, network_mode, namespaces and volumes_from.
+	Kinds []DependencyKind
+	// Condition is the condition of depends_on. It is empty if Kinds does not contain DependencyDependsOn.
+	Condition string
+}
+
+// DependencyGraph is a dependency graph of services of a project.
+//
+// Nodes are enabled services and services they refer to, which may be disabled or missing in the project.
+// Containers referred by "container:" prefix are not part of the graph.
+type DependencyGraph struct {
+	services []string
+	edges    []DependencyEdge
+	deps     map[string][]string
+	rdeps    map[string][]string
+}
+
+// NewDependencyGraph builds a DependencyGraph of enabled services of project.
+func NewDependencyGraph(project *types.Project) *DependencyGraph {
+	g := &DependencyGraph{
+		deps:  make(map[string][]string),
+		rdeps: make(map[string][]string),
+	}
+	edges := make(map[[2]string]*DependencyEdge)
+	add := func(from, to string, kind DependencyKind, condition string) {
+		key := [2]string{from, to}
+		edge, ok := edges[key]
+		if !ok {
+			edge = &DependencyEdge{From: from, To: to}
+			edges[key] = edge
+		}
+		if !slices.Contains(edge.Kinds, kind) {
+			edge.Kinds = append(edge.Kinds, kind)
+		}
+		if condition != "" {
+			edge.Condition = condition
+		}
+	}
+
+	for _, service := range project.Services {
+		g.deps[service.Name] = nil
+		for name, dep := range service.DependsOn {
+			condition := dep.Condition
+			if condition == "" {
+				condition = types.ServiceConditionStarted
+			}
+			add(service.Name, name, DependencyDependsOn, condition)
+		}
+		for _, link := range service.Links {
+			name, _, _ := strings.Cut(link, ":")
+			add(service.Name, name, DependencyLinks, "")
+		}
+		if name, ok := strings.CutPrefix(service.NetworkMode, types.ServicePrefix); ok {
+			add(service.Name, name, DependencyNetworkMode, "")
+		}
+		for _, namespace := range []string{service.Ipc, service.Pid, service.Uts, service.Cgroup} {
+			if name, ok := strings.CutPrefix(namespace, types.ServicePrefix); ok {
+				add(service.Name, name, DependencyNamespace, "")
+			}
+		}
+		for _, volume := range service.VolumesFrom {
+			if strings.HasPrefix(volume, types.ContainerPrefix) {
+				continue
+			}
+			name, _, _ := strings.Cut(volume, ":")
+			add(service.Name, name, DependencyVolumesFrom, "")
+		}
+	}
+
+	for _, edge := range edges {
+		slices.Sort(edge.Kinds)
+		g.edges = append(g.edges, *edge)
+		g.deps[edge.From] = append(g.deps[edge.From], edge.To)
+		g.rdeps[edge.To] = append(g.rdeps[edge.To], edge.From)
+		if _, ok := g.deps[edge.To]; !ok {
+			g.deps[edge.To] = nil
+		}
+	}
+	slices.SortFunc(g.edges, func(i, j DependencyEdge) int {
+		if c := strings.Compare(i.From, j.From); c != 0 {
+			return c
+		}
+		return strings.Compare(i.To, j.To)
+	})
+	for name := range g.deps {
+		slices.Sort(g.deps[name])
+		slices.Sort(g.rdeps[name])
+	}
+	g.services = sortedKeys(g.deps)
+	return g
+}
+
+// Services returns names of all nodes, sorted.
+func (g *DependencyGraph) Services() []string {
+	return slices.Clone(g.services)
+}
+
+// Edges returns all edges, sorted by From then To.
+func (g *DependencyGraph) Edges() []DependencyEdge {
+	edges := make([]DependencyEdge, len(g.edges))
+	for i, edge := range g.edges {
+		edge.Kinds = slices.Clone(edge.Kinds)
+		edges[i] = edge
+	}
+	return edges
+}
+
+// Dependencies returns services which service directly depends on, sorted.
+func (g *DependencyGraph) Dependencies(service string) []string {
+	return slices.Clone(g.deps[service])
+}
+
+// Dependents returns services which directly depend on service, sorted.
+func (g *DependencyGraph) Dependents(service string) []string {
+	return slices.Clone(g.rdeps[service])
+}
+
+// TransitiveDependencies returns services which service depends on directly or indirectly, sorted.
+// service itself is not included unless it is in a cycle.
+func (g *DependencyGraph) TransitiveDependencies(service string) []string {
+	return closure(g.deps, service)
+}
+
+// TransitiveDependents returns services which depend on service directly or indirectly, sorted.
+// service itself is not included unless it is in a cycle.
+func (g *DependencyGraph) TransitiveDependents(service string) []string {
+	return closure(g.rdeps, service)
+}
+
+func closure(edges map[string][]string, from string) []string {
+	seen := make(map[string]bool)
+	stack := slices.Clone(edges[from])
+	for len(stack) > 0 {
+		name := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		stack = append(stack, edges[name]...)
+	}
+	return sortedKeys(seen)
+}
+
+// TopologicalOrder returns services so that each comes after its dependencies, ties broken by name.
+// It returns an error if the graph has cycles.
+func (g *DependencyGraph) TopologicalOrder() ([]string, error) {
+	if cycles := g.Cycles(); len(cycles) > 0 {
+		descriptions := make([]string, len(cycles))
+		for i, cycle := range cycles {
+			descriptions[i] = "[" + strings.Join(cycle, ", ") + "]"
+		}
+		return nil, fmt.Errorf("services have cyclic dependency: %s", strings.Join(descriptions, ", "))
+	}
+
+	var (
+		order   []string
+		pending = make(map[string]int, len(g.services))
+		ready   []string
+	)
+	for _, name := range g.services {
+		pending[name] = len(g.deps[name])
+		if pending[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+	for len(ready) > 0 {
+		name := ready[0]
+		ready = ready[1:]
+		order = append(order, name)
+		for _, dependent := range g.rdeps[name] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				ready = append(ready, dependent)
+				slices.Sort(ready)
+			}
+		}
+	}
+	return order, nil
+}
+
+// Cycles returns sets of services which depend on each other, each sorted, ordered by their first service.
+// A service depending on itself is a cycle.
+func (g *DependencyGraph) Cycles() [][]string {
+	// Tarjan's strongly connected components.
+	var (
+		index   = make(map[string]int)
+		lowlink = make(map[string]int)
+		onStack = make(map[string]bool)
+		stack   []string
+		cycles  [][]string
+		visit   func(name string)
+	)
+	visit = func(name string) {
+		index[name] = len(index)
+		lowlink[name] = index[name]
+		stack = append(stack, name)
+		onStack[name] = true
+		for _, dep := range g.deps[name] {
+			if _, ok := index[dep]; !ok {
+				visit(dep)
+				lowlink[name] = min(lowlink[name], lowlink[dep])
+			} else if onStack[dep] {
+				lowlink[name] = min(lowlink[name], index[dep])
+			}
+		}
+		if lowlink[name] != index[name] {
+			return
+		}
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == name {
+				break
+			}
+		}
+		if len(component) > 1 || slices.Contains(g.deps[name], name) {
+			slices.Sort(component)
+			cycles = append(cycles, component)
+		}
+	}
+	for _, name := range g.services {
+		if _, ok := index[name]; !ok {
+			visit(name)
+		}
+	}
+	slices.SortFunc(cycles, func(i, j []string) int { return strings.Compare(i[0], j[0]) })
+	return cycles
+}
+
+// DOT renders the graph in Graphviz DOT language.
+// Edges point from dependents to dependencies and are labeled with their kinds and depends_on condition.
+func (g *DependencyGraph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph {\n")
+	for _, name := range g.services {
+		fmt.Fprintf(&b, "  %q;\n", name)
+	}
+	for _, edge := range g.edges {
+		fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", edge.From, edge.To, edge.label())
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart.
+// Edges point from dependents to dependencies and are labeled with their kinds and depends_on condition.
+func (g *DependencyGraph) Mermaid() string {
+	// service names may contain characters which Mermaid does not allow in ids, e.g. ".".
+	ids := make(map[string]string, len(g.services))
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for i, name := range g.services {
+		ids[name] = fmt.Sprintf("s%d", i)
+		fmt.Fprintf(&b, "  %s[%q]\n", ids[name], name)
+	}
+	for _, edge := range g.edges {
+		fmt.Fprintf(&b, "  %s -->|%q| %s\n", ids[edge.From], edge.label(), ids[edge.To])
+	}
+	return b.String()
+}
+
+func (e DependencyEdge) label() string {
+	kinds := make([]string, len(e.Kinds))
+	for i, kind := range e.Kinds {
+		kinds[i] = string(kind)
+		if kind == DependencyDependsOn {
+			kinds[i] += ": " + e.Condition
+		}
+	}
+	return strings.Join(kinds, ", ")
+}
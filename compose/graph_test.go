@@ -0,0 +1,110 @@
+package compose
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const graphComposeYaml = `services:
+  postgres:
+    image: postgres:16
+  app:
+    image: ubuntu:jammy-20230624
+    depends_on:
+      postgres:
+        condition: service_healthy
+    links: ["cache:redis"]
+  cache:
+    image: redis:7
+  vpn:
+    image: ubuntu:jammy-20230624
+  worker:
+    image: ubuntu:jammy-20230624
+    network_mode: service:vpn
+    pid: service:app
+    volumes_from: ["app:ro", "container:external"]
+`
+
+func TestDependencyGraph(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	loader, err := NewLoaderProxy("graph", types.ConfigDetails{
+		WorkingDir:  "./testdata",
+		ConfigFiles: []types.ConfigFile{{Filename: "./testdata/graph.yml", Content: []byte(graphComposeYaml)}},
+		Environment: types.NewMapping(os.Environ()),
+	}, nil, nil)
+	require.NoError(err)
+	project, err := loader.Load(context.Background())
+	require.NoError(err)
+
+	g := NewDependencyGraph(project)
+	assert.Equal([]string{"app", "cache", "postgres", "vpn", "worker"}, g.Services())
+	assert.Equal([]DependencyEdge{
+		{From: "app", To: "cache", Kinds: []DependencyKind{DependencyDependsOn, DependencyLinks}, Condition: types.ServiceConditionStarted},
+		{From: "app", To: "postgres", Kinds: []DependencyKind{DependencyDependsOn}, Condition: types.ServiceConditionHealthy},
+		{From: "worker", To: "app", Kinds: []DependencyKind{DependencyDependsOn, DependencyNamespace, DependencyVolumesFrom}, Condition: types.ServiceConditionStarted},
+		{From: "worker", To: "vpn", Kinds: []DependencyKind{DependencyDependsOn, DependencyNetworkMode}, Condition: types.ServiceConditionStarted},
+	}, g.Edges())
+
+	assert.Equal([]string{"cache", "postgres"}, g.Dependencies("app"))
+	assert.Equal([]string{"app"}, g.Dependents("postgres"))
+	assert.Equal([]string{"app", "worker"}, g.TransitiveDependents("postgres"))
+	assert.Equal([]string{"app", "cache", "postgres", "vpn"}, g.TransitiveDependencies("worker"))
+	assert.Empty(g.TransitiveDependencies("postgres"))
+	assert.Empty(g.Cycles())
+
+	order, err := g.TopologicalOrder()
+	require.NoError(err)
+	assert.Equal([]string{"cache", "postgres", "app", "vpn", "worker"}, order)
+
+	assert.Equal(`digraph {
+  "app";
+  "cache";
+  "postgres";
+  "vpn";
+  "worker";
+  "app" -> "cache" [label="depends_on: service_started, links"];
+  "app" -> "postgres" [label="depends_on: service_healthy"];
+  "worker" -> "app" [label="depends_on: service_started, namespace, volumes_from"];
+  "worker" -> "vpn" [label="depends_on: service_started, network_mode"];
+}
+`, g.DOT())
+	assert.Equal(`flowchart LR
+  s0["app"]
+  s1["cache"]
+  s2["postgres"]
+  s3["vpn"]
+  s4["worker"]
+  s0 -->|"depends_on: service_started, links"| s1
+  s0 -->|"depends_on: service_healthy"| s2
+  s4 -->|"depends_on: service_started, namespace, volumes_from"| s0
+  s4 -->|"depends_on: service_started, network_mode"| s3
+`, g.Mermaid())
+}
+
+func TestDependencyGraph_cycles(t *testing.T) {
+	assert := assert.New(t)
+
+	g := NewDependencyGraph(&types.Project{
+		Services: types.Services{
+			{Name: "a", DependsOn: types.DependsOnConfig{"b": {}}},
+			{Name: "b", Links: []string{"c"}},
+			{Name: "c", VolumesFrom: []string{"a"}},
+			{Name: "d", NetworkMode: "service:d"},
+			{Name: "e", DependsOn: types.DependsOnConfig{"a": {}, "missing": {}}},
+		},
+	})
+	assert.Equal([][]string{{"a", "b", "c"}, {"d"}}, g.Cycles())
+	assert.Equal([]string{"a", "b", "c"}, g.TransitiveDependencies("a"))
+	assert.Equal([]string{"a", "b", "c", "e"}, g.TransitiveDependents("c"))
+	assert.Contains(g.Services(), "missing")
+
+	_, err := g.TopologicalOrder()
+	assert.EqualError(err, "services have cyclic dependency: [a, b, c], [d]")
+}
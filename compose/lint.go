@@ -0,0 +1,428 @@
+package compose
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/distribution/reference"
+)
+
+// Severity is the severity of a LintIssue.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota + 1
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return "Severity(" + strconv.Itoa(int(s)) + ")"
+}
+
+// LintIssue is an issue found by a LintRule.
+type LintIssue struct {
+	// Rule is the name of the rule which reported the issue. Linter fills it if empty.
+	Rule     string
+	Severity Severity
+	// Service is the name of the service which the issue is about. It is empty for project wide issues.
+	Service string
+	Message string
+}
+
+func (i LintIssue) String() string {
+	if i.Service == "" {
+		return fmt.Sprintf("%s: %s: %s", i.Severity, i.Rule, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: service %q: %s", i.Severity, i.Rule, i.Service, i.Message)
+}
+
+// LintRule checks a project.
+type LintRule interface {
+	// Name returns the name of the rule, which is used to override severity or to ignore the rule.
+	Name() string
+	// Check returns issues found in project. Only enabled services should be checked.
+	Check(project *types.Project) []LintIssue
+}
+
+type lintRuleFunc struct {
+	name  string
+	check func(project *types.Project) []LintIssue
+}
+
+func (r lintRuleFunc) Name() string                             { return r.name }
+func (r lintRuleFunc) Check(project *types.Project) []LintIssue { return r.check(project) }
+
+// NewLintRule returns a LintRule named name which checks project by check.
+func NewLintRule(name string, check func(project *types.Project) []LintIssue) LintRule {
+	return lintRuleFunc{name: name, check: check}
+}
+
+// Names of built-in rules.
+const (
+	LintRuleImageTag       = "image-tag"
+	LintRulePortConflict   = "port-conflict"
+	LintRuleHealthcheck    = "healthcheck"
+	LintRuleRestartPolicy  = "restart-policy"
+	LintRulePrivileged     = "privileged"
+	LintRuleBindMount      = "bind-mount"
+	LintRuleHostNamespaces = "host-namespaces"
+)
+
+// DefaultLintRules returns built-in rules.
+//
+//   - image-tag: warns images tagged latest or not tagged nor digested. Services which are built are ignored.
+//   - port-conflict: reports host ports published more than once, including by replicas of a service.
+//   - healthcheck: informs services without healthcheck. A healthcheck defined in the image is not taken into account.
+//   - restart-policy: warns services without restart nor deploy.restart_policy.
+//   - privileged: warns privileged services and ones adding ALL capabilities.
+//   - host-namespaces: warns services using network, pid, ipc or uts namespaces of the host.
+//   - bind-mount: warns bind mounts whose source is outside the working directory.
+func DefaultLintRules() []LintRule {
+	return []LintRule{
+		NewLintRule(LintRuleImageTag, checkImageTag),
+		NewLintRule(LintRulePortConflict, checkPortConflict),
+		NewLintRule(LintRuleHealthcheck, checkHealthcheck),
+		NewLintRule(LintRuleRestartPolicy, checkRestartPolicy),
+		NewLintRule(LintRulePrivileged, checkPrivileged),
+		NewLintRule(LintRuleHostNamespaces, checkHostNamespaces),
+		NewLintRule(LintRuleBindMount, checkBindMount),
+	}
+}
+
+// LinterOptions is options for NewLinter.
+type LinterOptions struct {
+	// Rules defaults to DefaultLintRules().
+	Rules []LintRule
+	// Severities overrides severities of issues reported by rules, keyed by rule name.
+	Severities map[string]Severity
+	// Ignore is names of rules not to run.
+	Ignore []string
+	// FailOn is the minimum severity of issues which fail Op. It defaults to SeverityError.
+	FailOn Severity
+}
+
+// Linter checks projects by rules.
+type Linter struct {
+	options LinterOptions
+}
+
+// NewLinter returns a new Linter.
+func NewLinter(options LinterOptions) *Linter {
+	if options.Rules == nil {
+		options.Rules = DefaultLintRules()
+	}
+	if options.FailOn == 0 {
+		options.FailOn = SeverityError
+	}
+	return &Linter{options: options}
+}
+
+// Lint runs rules over project and returns issues ordered by rule, then by service.
+func (l *Linter) Lint(project *types.Project) []LintIssue {
+	var issues []LintIssue
+	for _, rule := range l.options.Rules {
+		if slices.Contains(l.options.Ignore, rule.Name()) {
+			continue
+		}
+		found := rule.Check(project)
+		slices.SortStableFunc(found, func(i, j LintIssue) int { return strings.Compare(i.Service, j.Service) })
+		for _, issue := range found {
+			if issue.Rule == "" {
+				issue.Rule = rule.Name()
+			}
+			if severity, ok := l.options.Severities[rule.Name()]; ok {
+				issue.Severity = severity
+			}
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// Op returns a function which lints the project and fails with *LintError
+// if any issue is at or above options.FailOn.
+// It can be passed to LoadComposeService as one of ops.
+func (l *Linter) Op() func(p *types.Project) error {
+	return func(p *types.Project) error {
+		issues := l.Lint(p)
+		for _, issue := range issues {
+			if issue.Severity >= l.options.FailOn {
+				return &LintError{Issues: issues}
+			}
+		}
+		return nil
+	}
+}
+
+// LintError is returned by the function returned from (*Linter).Op.
+// Issues has all issues found, including ones below LinterOptions.FailOn.
+type LintError struct {
+	Issues []LintIssue
+}
+
+func (e *LintError) Error() string {
+	var b strings.Builder
+	b.WriteString("lint failed:")
+	for _, issue := range e.Issues {
+		b.WriteString("\n\t")
+		b.WriteString(issue.String())
+	}
+	return b.String()
+}
+
+func checkImageTag(project *types.Project) []LintIssue {
+	var issues []LintIssue
+	for _, service := range project.Services {
+		if service.Build != nil || service.Image == "" {
+			continue
+		}
+		named, err := reference.ParseNormalizedNamed(service.Image)
+		if err != nil {
+			issues = append(issues, LintIssue{
+				Severity: SeverityError,
+				Service:  service.Name,
+				Message:  fmt.Sprintf("invalid image %q: %s", service.Image, err),
+			})
+			continue
+		}
+		if _, ok := named.(reference.Digested); ok {
+			continue
+		}
+		tagged, ok := named.(reference.Tagged)
+		switch {
+		case !ok:
+			issues = append(issues, LintIssue{
+				Severity: SeverityWarning,
+				Service:  service.Name,
+				Message:  fmt.Sprintf("image %q is not pinned to a tag or digest", service.Image),
+			})
+		case tagged.Tag() == "latest":
+			issues = append(issues, LintIssue{
+				Severity: SeverityWarning,
+				Service:  service.Name,
+				Message:  fmt.Sprintf("image %q uses the latest tag", service.Image),
+			})
+		}
+	}
+	return issues
+}
+
+// publishedPort is a host port published by a service.
+type publishedPort struct {
+	HostIP   string
+	Port     int
+	Protocol string
+	Target   uint32
+}
+
+// overlaps reports whether p and other bind the same host port.
+func (p publishedPort) overlaps(other publishedPort) bool {
+	return p.Port == other.Port &&
+		p.Protocol == other.Protocol &&
+		(p.HostIP == other.HostIP || isAnyAddr(p.HostIP) || isAnyAddr(other.HostIP))
+}
+
+func (p publishedPort) String() string {
+	hostIP := p.HostIP
+	if hostIP == "" {
+		hostIP = "0.0.0.0"
+	}
+	return fmt.Sprintf("%s/%s", net.JoinHostPort(hostIP, strconv.Itoa(p.Port)), p.Protocol)
+}
+
+func isAnyAddr(ip string) bool {
+	return ip == "" || ip == "0.0.0.0" || ip == "::"
+}
+
+// publishedPorts returns host ports published by service, expanding ranges.
+// Ports without published ones, which docker assigns randomly, are ignored.
+func publishedPorts(service types.ServiceConfig) ([]publishedPort, error) {
+	var ports []publishedPort
+	for _, port := range service.Ports {
+		if port.Published == "" {
+			continue
+		}
+		protocol := port.Protocol
+		if protocol == "" {
+			protocol = "tcp"
+		}
+		start, end, err := parsePortRange(port.Published)
+		if err != nil {
+			return nil, err
+		}
+		for p := start; p <= end; p++ {
+			ports = append(ports, publishedPort{HostIP: port.HostIP, Port: p, Protocol: protocol, Target: port.Target})
+		}
+	}
+	return ports, nil
+}
+
+func parsePortRange(published string) (start, end int, err error) {
+	startStr, endStr, isRange := strings.Cut(published, "-")
+	start, err = strconv.Atoi(startStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid published port %q: %w", published, err)
+	}
+	end = start
+	if isRange {
+		end, err = strconv.Atoi(endStr)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid published port %q: %w", published, err)
+		}
+	}
+	if start <= 0 || end > 65535 || start > end {
+		return 0, 0, fmt.Errorf("invalid published port %q", published)
+	}
+	return start, end, nil
+}
+
+func checkPortConflict(project *types.Project) []LintIssue {
+	var (
+		issues []LintIssue
+		seen   []publishedPort
+		owners []string
+	)
+	// services are in no particular order after loading.
+	services := slices.Clone(project.Services)
+	slices.SortFunc(services, func(i, j types.ServiceConfig) int { return strings.Compare(i.Name, j.Name) })
+	for _, service := range services {
+		ports, err := publishedPorts(service)
+		if err != nil {
+			issues = append(issues, LintIssue{Severity: SeverityError, Service: service.Name, Message: err.Error()})
+			continue
+		}
+		// a range can be shared by replicas since docker picks a free port out of it.
+		fixed := slices.ContainsFunc(service.Ports, func(p types.ServicePortConfig) bool {
+			return p.Published != "" && !strings.Contains(p.Published, "-")
+		})
+		if fixed && service.Deploy != nil && service.Deploy.Replicas != nil && *service.Deploy.Replicas > 1 {
+			issues = append(issues, LintIssue{
+				Severity: SeverityError,
+				Service:  service.Name,
+				Message:  fmt.Sprintf("%d replicas can not publish the same fixed host port", *service.Deploy.Replicas),
+			})
+		}
+		for _, port := range ports {
+			for i, other := range seen {
+				if port.overlaps(other) {
+					message := fmt.Sprintf("host port %s is also published by service %q", port, owners[i])
+					if owners[i] == service.Name {
+						message = fmt.Sprintf("host port %s is published more than once", port)
+					}
+					issues = append(issues, LintIssue{Severity: SeverityError, Service: service.Name, Message: message})
+					break
+				}
+			}
+			seen = append(seen, port)
+			owners = append(owners, service.Name)
+		}
+	}
+	return issues
+}
+
+func checkHealthcheck(project *types.Project) []LintIssue {
+	var issues []LintIssue
+	for _, service := range project.Services {
+		if service.HealthCheck == nil || service.HealthCheck.Disable {
+			issues = append(issues, LintIssue{
+				Severity: SeverityInfo,
+				Service:  service.Name,
+				Message:  "no healthcheck is defined",
+			})
+		}
+	}
+	return issues
+}
+
+func checkRestartPolicy(project *types.Project) []LintIssue {
+	var issues []LintIssue
+	for _, service := range project.Services {
+		if service.Restart != "" || (service.Deploy != nil && service.Deploy.RestartPolicy != nil) {
+			continue
+		}
+		issues = append(issues, LintIssue{
+			Severity: SeverityWarning,
+			Service:  service.Name,
+			Message:  "no restart policy is defined",
+		})
+	}
+	return issues
+}
+
+func checkPrivileged(project *types.Project) []LintIssue {
+	var issues []LintIssue
+	for _, service := range project.Services {
+		if service.Privileged {
+			issues = append(issues, LintIssue{
+				Severity: SeverityWarning,
+				Service:  service.Name,
+				Message:  "runs privileged",
+			})
+		}
+		if slices.ContainsFunc(service.CapAdd, func(c string) bool { return strings.EqualFold(c, "ALL") }) {
+			issues = append(issues, LintIssue{
+				Severity: SeverityWarning,
+				Service:  service.Name,
+				Message:  "adds ALL capabilities",
+			})
+		}
+	}
+	return issues
+}
+
+func checkHostNamespaces(project *types.Project) []LintIssue {
+	var issues []LintIssue
+	for _, service := range project.Services {
+		for _, ns := range []struct{ name, mode string }{
+			{"network_mode", service.NetworkMode},
+			{"pid", service.Pid},
+			{"ipc", service.Ipc},
+			{"uts", service.Uts},
+		} {
+			if ns.mode == "host" {
+				issues = append(issues, LintIssue{
+					Severity: SeverityWarning,
+					Service:  service.Name,
+					Message:  fmt.Sprintf("%s is host", ns.name),
+				})
+			}
+		}
+	}
+	return issues
+}
+
+func checkBindMount(project *types.Project) []LintIssue {
+	var issues []LintIssue
+	for _, service := range project.Services {
+		for _, volume := range service.Volumes {
+			if volume.Type != types.VolumeTypeBind || volume.Source == "" {
+				continue
+			}
+			source := absPath(project.WorkingDir, volume.Source)
+			rel, err := filepath.Rel(absPath("", project.WorkingDir), source)
+			if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				continue
+			}
+			issues = append(issues, LintIssue{
+				Severity: SeverityWarning,
+				Service:  service.Name,
+				Message:  fmt.Sprintf("bind mount source %q is outside the working directory", volume.Source),
+			})
+		}
+	}
+	return issues
+}
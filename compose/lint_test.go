@@ -0,0 +1,103 @@
+package compose
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const lintComposeYaml = `services:
+  pinned:
+    image: ubuntu:jammy-20230624
+    restart: always
+    healthcheck:
+      test: ["CMD", "true"]
+    ports: ["8080:80"]
+    volumes: ["./data:/data"]
+  latest:
+    image: ubuntu:latest
+    restart: always
+    healthcheck:
+      test: ["CMD", "true"]
+    ports: ["127.0.0.1:8080:80"]
+  untagged:
+    image: ubuntu
+    privileged: true
+    network_mode: host
+    volumes: ["/var/run/docker.sock:/var/run/docker.sock"]
+  scaled:
+    image: ubuntu:jammy-20230624
+    ports: ["9000:80", "9001-9002:81/udp"]
+    deploy:
+      replicas: 2
+      restart_policy:
+        condition: on-failure
+    healthcheck:
+      test: ["CMD", "true"]
+  built:
+    build: .
+    restart: always
+    healthcheck:
+      test: ["CMD", "true"]
+    ports: ["9001:81/tcp"]
+`
+
+func TestLinter(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	loader, err := NewLoaderProxy("lint", types.ConfigDetails{
+		WorkingDir:  "./testdata",
+		ConfigFiles: []types.ConfigFile{{Filename: "./testdata/lint.yml", Content: []byte(lintComposeYaml)}},
+		Environment: types.NewMapping(os.Environ()),
+	}, nil, nil)
+	require.NoError(err)
+	p, err := loader.Load(context.Background())
+	require.NoError(err)
+
+	issues := NewLinter(LinterOptions{}).Lint(p)
+	assert.Equal([]LintIssue{
+		{Rule: LintRuleImageTag, Severity: SeverityWarning, Service: "latest", Message: `image "ubuntu:latest" uses the latest tag`},
+		{Rule: LintRuleImageTag, Severity: SeverityWarning, Service: "untagged", Message: `image "ubuntu" is not pinned to a tag or digest`},
+		{Rule: LintRulePortConflict, Severity: SeverityError, Service: "pinned", Message: `host port 0.0.0.0:8080/tcp is also published by service "latest"`},
+		{Rule: LintRulePortConflict, Severity: SeverityError, Service: "scaled", Message: `2 replicas can not publish the same fixed host port`},
+		{Rule: LintRuleHealthcheck, Severity: SeverityInfo, Service: "untagged", Message: "no healthcheck is defined"},
+		{Rule: LintRuleRestartPolicy, Severity: SeverityWarning, Service: "untagged", Message: "no restart policy is defined"},
+		{Rule: LintRulePrivileged, Severity: SeverityWarning, Service: "untagged", Message: "runs privileged"},
+		{Rule: LintRuleHostNamespaces, Severity: SeverityWarning, Service: "untagged", Message: "network_mode is host"},
+		{Rule: LintRuleBindMount, Severity: SeverityWarning, Service: "untagged", Message: `bind mount source "/var/run/docker.sock" is outside the working directory`},
+	}, issues)
+
+	err = NewLinter(LinterOptions{}).Op()(p)
+	var lintErr *LintError
+	require.True(errors.As(err, &lintErr))
+	assert.Equal(issues, lintErr.Issues)
+
+	linter := NewLinter(LinterOptions{
+		Severities: map[string]Severity{LintRulePortConflict: SeverityWarning},
+		Ignore:     []string{LintRuleHealthcheck, LintRuleBindMount},
+	})
+	assert.NoError(linter.Op()(p))
+	assert.Len(linter.Lint(p), 7)
+
+	linter = NewLinter(LinterOptions{
+		Rules: []LintRule{
+			NewLintRule("no-services", func(project *types.Project) []LintIssue {
+				if len(project.Services) > 0 {
+					return nil
+				}
+				return []LintIssue{{Severity: SeverityError, Message: "no services"}}
+			}),
+		},
+	})
+	assert.NoError(linter.Op()(p))
+	assert.EqualError(
+		linter.Op()(&types.Project{}),
+		"lint failed:\n\terror: no-services: no services",
+	)
+}
@@ -157,6 +157,16 @@ func (l *Loader) Load(ctx context.Context) (*types.Project, error) {
 	)
 }
 
+// Config loads the project and renders it as `docker compose config` does.
+// Environment variables are rendered as resolved by Load unless options.RedactEnvironment is true.
+func (l *Loader) Config(ctx context.Context, options RenderOptions) (RenderedConfig, error) {
+	project, err := l.Load(ctx)
+	if err != nil {
+		return RenderedConfig{}, err
+	}
+	return RenderConfig(ctx, project, l.DockerCli, options)
+}
+
 func (l *Loader) LoadComposeService(ctx context.Context, ops ...func(p *types.Project) error) (*ComposeService, error) {
 	project, err := l.Load(ctx)
 	if err != nil {
@@ -0,0 +1,156 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/compose-spec/compose-go/loader"
+	"github.com/compose-spec/compose-go/types"
+)
+
+// FSConfigDetails describes a compose project stored in an fs.FS, e.g. embed.FS.
+//
+// WorkingDir in FS is mapped onto HostDir on the host.
+// A path relative to the working directory, e.g. "./secrets/token.txt",
+// refers to WorkingDir/secrets/token.txt in FS and to HostDir/secrets/token.txt on the host.
+//
+// Compose files are read from FS into memory.
+// Env files, and files of secrets and configs which reside under WorkingDir in FS, are extracted to HostDir,
+// since compose reads env files from the host and the docker daemon bind mounts secret and config files from the host.
+// Paths which do not reside under WorkingDir in FS, e.g. absolute ones, are left as is and refer to the host.
+type FSConfigDetails struct {
+	FS fs.FS
+	// WorkingDir is a slash separated path in FS. It defaults to the directory of the first compose file.
+	WorkingDir string
+	// ConfigFiles are slash separated paths of compose files in FS.
+	ConfigFiles []string
+	// HostDir is the working directory of the project on the host. It must not be empty.
+	HostDir     string
+	Environment types.Mapping
+}
+
+// LoadConfigDetailsFS reads compose files from details.FS, extracts files referenced by them to details.HostDir
+// and returns types.ConfigDetails which loads the project as if it were stored in details.HostDir.
+// Compose files themselves are not extracted. The returned ConfigDetails has their contents preloaded.
+//
+// options are used to find referenced files, as they are passed to the loader, e.g. to enable profiles.
+// Files referenced via extends or include are not supported.
+func LoadConfigDetailsFS(ctx context.Context, details FSConfigDetails, options ...func(*loader.Options)) (types.ConfigDetails, error) {
+	if len(details.ConfigFiles) == 0 {
+		return types.ConfigDetails{}, fmt.Errorf("ConfigFiles must not be empty")
+	}
+	if details.HostDir == "" {
+		return types.ConfigDetails{}, fmt.Errorf("HostDir must not be empty")
+	}
+	hostDir, err := filepath.Abs(details.HostDir)
+	if err != nil {
+		return types.ConfigDetails{}, err
+	}
+	workingDir := details.WorkingDir
+	if workingDir == "" {
+		workingDir = path.Dir(details.ConfigFiles[0])
+	}
+	workingDir = path.Clean(workingDir)
+
+	configDetails := types.ConfigDetails{
+		WorkingDir:  hostDir,
+		Environment: details.Environment.Clone(),
+	}
+	for _, name := range details.ConfigFiles {
+		content, err := fs.ReadFile(details.FS, name)
+		if err != nil {
+			return types.ConfigDetails{}, err
+		}
+		configDetails.ConfigFiles = append(configDetails.ConfigFiles, types.ConfigFile{
+			Filename: hostPath(workingDir, hostDir, name),
+			Content:  content,
+		})
+	}
+	configDetails, err = PreloadConfigDetails(configDetails)
+	if err != nil {
+		return types.ConfigDetails{}, err
+	}
+
+	// Env files are read while loading, thus the project is loaded without resolving environment
+	// to find referenced files before extracting them.
+	probe, err := loader.LoadWithContext(
+		ctx,
+		cloneConfigDetails(configDetails),
+		append(
+			options,
+			func(o *loader.Options) {
+				o.SkipResolveEnvironment = true
+				o.SkipConsistencyCheck = true
+				o.SkipExtends = true
+				o.SkipInclude = true
+				o.SetProjectName("fs-probe", false)
+			},
+		)...,
+	)
+	if err != nil {
+		return types.ConfigDetails{}, err
+	}
+
+	for _, hostFile := range referencedFiles(probe) {
+		if err := extractFile(details.FS, workingDir, hostDir, hostFile); err != nil {
+			return types.ConfigDetails{}, err
+		}
+	}
+	return configDetails, nil
+}
+
+// hostPath maps name in FS onto the host.
+func hostPath(workingDir, hostDir, name string) string {
+	rel := strings.TrimPrefix(path.Clean(name), workingDir+"/")
+	if workingDir == "." {
+		rel = path.Clean(name)
+	}
+	return filepath.Join(hostDir, filepath.FromSlash(rel))
+}
+
+// referencedFiles returns paths of files on the host which project reads or mounts.
+func referencedFiles(project *types.Project) []string {
+	var files []string
+	for _, service := range append(slices.Clone(project.Services), project.DisabledServices...) {
+		for _, envFile := range service.EnvFile {
+			files = append(files, absPath(project.WorkingDir, envFile))
+		}
+	}
+	for _, secret := range project.Secrets {
+		if secret.File != "" {
+			files = append(files, absPath(project.WorkingDir, secret.File))
+		}
+	}
+	for _, config := range project.Configs {
+		if config.File != "" {
+			files = append(files, absPath(project.WorkingDir, config.File))
+		}
+	}
+	return files
+}
+
+// extractFile copies the file in fsys which hostFile is mapped from, if hostFile is under hostDir and the file exists.
+func extractFile(fsys fs.FS, workingDir, hostDir, hostFile string) error {
+	rel, err := filepath.Rel(hostDir, hostFile)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil
+	}
+	name := path.Join(workingDir, filepath.ToSlash(rel))
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(hostFile), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(hostFile, content, 0o644)
+}
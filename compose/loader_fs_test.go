@@ -0,0 +1,80 @@
+package compose
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigDetailsFS(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fsys := fstest.MapFS{
+		"deploy/compose.yml": {Data: []byte(`services:
+  app:
+    image: ubuntu:jammy-20230624
+    env_file: [app.env]
+    secrets: [token]
+    configs: [conf]
+secrets:
+  token:
+    file: ./secrets/token.txt
+configs:
+  conf:
+    file: /etc/hostname
+`)},
+		"deploy/override.yml": {Data: []byte(`services:
+  app:
+    environment:
+      BAR: bar
+`)},
+		"deploy/app.env":            {Data: []byte("FOO=foo\n")},
+		"deploy/secrets/token.txt":  {Data: []byte("secret")},
+		"deploy/secrets/unused.txt": {Data: []byte("unused")},
+	}
+	hostDir := t.TempDir()
+
+	details, err := LoadConfigDetailsFS(context.Background(), FSConfigDetails{
+		FS:          fsys,
+		ConfigFiles: []string{"deploy/compose.yml", "deploy/override.yml"},
+		HostDir:     hostDir,
+		Environment: types.NewMapping(os.Environ()),
+	})
+	require.NoError(err)
+	assert.Equal(hostDir, details.WorkingDir)
+	require.Len(details.ConfigFiles, 2)
+	assert.Equal(filepath.Join(hostDir, "compose.yml"), details.ConfigFiles[0].Filename)
+	assert.NotEmpty(details.ConfigFiles[1].Content)
+
+	// compose files and unreferenced files are not extracted.
+	for _, name := range []string{"compose.yml", "secrets/unused.txt"} {
+		_, err := os.Stat(filepath.Join(hostDir, name))
+		assert.ErrorIs(err, os.ErrNotExist)
+	}
+	token, err := os.ReadFile(filepath.Join(hostDir, "secrets", "token.txt"))
+	require.NoError(err)
+	assert.Equal("secret", string(token))
+
+	loader, err := NewLoaderProxy("embedded", details, nil, nil)
+	require.NoError(err)
+	project, err := loader.Load(context.Background())
+	require.NoError(err)
+	app, err := project.GetService("app")
+	require.NoError(err)
+	assert.Equal("foo", *app.Environment["FOO"])
+	assert.Equal("bar", *app.Environment["BAR"])
+	assert.Equal(filepath.Join(hostDir, "secrets", "token.txt"), project.Secrets["token"].File)
+	assert.Equal("/etc/hostname", project.Configs["conf"].File)
+
+	_, err = LoadConfigDetailsFS(context.Background(), FSConfigDetails{FS: fsys, ConfigFiles: []string{"deploy/compose.yml"}})
+	assert.Error(err)
+	_, err = LoadConfigDetailsFS(context.Background(), FSConfigDetails{FS: fsys, ConfigFiles: []string{"nonexistent.yml"}, HostDir: hostDir})
+	assert.Error(err)
+}
@@ -50,6 +50,12 @@ func (p *LoaderProxy) Load(ctx context.Context) (*types.Project, error) {
 	return p.loader.Load(ctx)
 }
 
+func (p *LoaderProxy) Config(ctx context.Context, options RenderOptions) (RenderedConfig, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.loader.Config(ctx, options)
+}
+
 func (p *LoaderProxy) LoadComposeService(ctx context.Context, ops ...func(p *types.Project) error) (*ComposeService, error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
@@ -0,0 +1,242 @@
+package compose
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/docker/compose/v2/pkg/api"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// LogStream is the stream which a log line is written to.
+type LogStream string
+
+const (
+	Stdout LogStream = "stdout"
+	Stderr LogStream = "stderr"
+)
+
+// LogRecord is a line of container logs.
+type LogRecord struct {
+	Service   string
+	Num       int
+	Container string
+	Stream    LogStream
+	// Timestamp is the time when the line is logged.
+	// It is zero if it is not available, e.g. a record is received through NewLogConsumer.
+	Timestamp time.Time
+	Line      string
+}
+
+// Logs executes the equivalent to a `compose logs`
+//
+// Unlike `compose logs`, Logs keeps stdout and stderr apart.
+// consumer is called with each line. Calls to consumer are serialized.
+// Lines of a container are delivered in order, while lines of different containers may interleave.
+//
+// Logs reads logs of containers which exist when it is called.
+// If options.Follow is true, Logs blocks until ctx is cancelled or all followed containers stop,
+// but containers created after the call are not followed.
+// options.Timestamps is ignored. LogRecord.Timestamp is always populated.
+//
+// Logs does not wait for other calls.
+func (s *ComposeService) Logs(ctx context.Context, consumer func(record LogRecord), options api.LogOptions) error {
+	c, ctx := s.begin(ctx)
+	defer c.end()
+	if options.Project == nil {
+		options.Project = c.project
+	}
+	if len(options.Services) == 0 {
+		options.Services = options.Project.ServiceNames()
+	}
+	containers, err := c.service.Ps(ctx, s.projectName, api.PsOptions{
+		Project:  options.Project,
+		All:      true,
+		Services: options.Services,
+	})
+	if err != nil {
+		return err
+	}
+	apiClient := c.cli.Client()
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs = make([]error, len(containers))
+	)
+	emit := func(record LogRecord) {
+		mu.Lock()
+		defer mu.Unlock()
+		consumer(record)
+	}
+	for i, container := range containers {
+		if container.Labels[api.OneoffLabel] == "True" {
+			continue
+		}
+		i, container := i, container
+		num, _ := strconv.Atoi(container.Labels[api.ContainerNumberLabel])
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = readContainerLogs(ctx, apiClient, container.ID, options, func(stream LogStream, line string) {
+				record := LogRecord{
+					Service:   container.Service,
+					Num:       num,
+					Container: container.Name,
+					Stream:    stream,
+				}
+				record.Timestamp, record.Line = readLogTimestamp(line)
+				emit(record)
+			})
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+type containerLogsClient interface {
+	ContainerInspect(ctx context.Context, container string) (dockertypes.ContainerJSON, error)
+	ContainerLogs(ctx context.Context, container string, options dockertypes.ContainerLogsOptions) (io.ReadCloser, error)
+}
+
+func readContainerLogs(
+	ctx context.Context,
+	apiClient containerLogsClient,
+	containerID string,
+	options api.LogOptions,
+	onLine func(stream LogStream, line string),
+) error {
+	inspected, err := apiClient.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return err
+	}
+
+	r, err := apiClient.ContainerLogs(ctx, containerID, dockertypes.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     options.Follow,
+		Since:      options.Since,
+		Until:      options.Until,
+		Tail:       options.Tail,
+		Timestamps: true,
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	stdout := newLineWriter(func(line string) { onLine(Stdout, line) })
+	stderr := newLineWriter(func(line string) { onLine(Stderr, line) })
+	defer stdout.flush()
+	defer stderr.flush()
+
+	if inspected.Config != nil && inspected.Config.Tty {
+		// Tty merges stderr into stdout.
+		_, err = io.Copy(stdout, r)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, r)
+	}
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// readLogTimestamp splits line into a timestamp prepended by the daemon and the rest.
+func readLogTimestamp(line string) (time.Time, string) {
+	tsStr, rest, found := strings.Cut(line, " ")
+	if !found {
+		return time.Time{}, line
+	}
+	ts, err := time.Parse(time.RFC3339Nano, tsStr)
+	if err != nil {
+		return time.Time{}, line
+	}
+	return ts, rest
+}
+
+// lineWriter calls onLine with each line written, without the trailing line feed.
+type lineWriter struct {
+	partial []byte
+	onLine  func(line string)
+}
+
+func newLineWriter(onLine func(line string)) *lineWriter {
+	return &lineWriter{onLine: onLine}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		idx := bytes.IndexByte(w.partial, '\n')
+		if idx < 0 {
+			break
+		}
+		w.onLine(strings.TrimSuffix(string(w.partial[:idx]), "\r"))
+		w.partial = w.partial[idx+1:]
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) flush() {
+	if len(w.partial) > 0 {
+		w.onLine(string(w.partial))
+	}
+	w.partial = nil
+}
+
+var _ api.LogConsumer = (*logConsumer)(nil)
+
+type logConsumer struct {
+	mu          sync.Mutex
+	projectName string
+	project     *types.Project
+	consumer    func(record LogRecord)
+}
+
+// NewLogConsumer returns an api.LogConsumer which converts lines into LogRecord and calls consumer with them.
+// It can be used as options.Start.Attach of ComposeService.Up.
+//
+// Lines passed to Log are reported as Stdout and lines passed to Err as Stderr.
+// Service and Num are resolved from the container name.
+// Timestamp of records is always zero since api.LogConsumer does not convey it.
+func NewLogConsumer(projectName string, project *types.Project, consumer func(record LogRecord)) api.LogConsumer {
+	return &logConsumer{
+		projectName: projectName,
+		project:     project,
+		consumer:    consumer,
+	}
+}
+
+func (c *logConsumer) Log(containerName, message string) {
+	c.emit(containerName, Stdout, message)
+}
+
+func (c *logConsumer) Err(containerName, message string) {
+	c.emit(containerName, Stderr, message)
+}
+
+func (c *logConsumer) Status(container, msg string) {}
+
+func (c *logConsumer) Register(container string) {}
+
+func (c *logConsumer) emit(containerName string, stream LogStream, message string) {
+	service, num, _ := resolveContainerName(containerName, c.projectName, c.project)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.consumer(LogRecord{
+		Service:   service,
+		Num:       num,
+		Container: containerName,
+		Stream:    stream,
+		Line:      message,
+	})
+}
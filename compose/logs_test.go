@@ -0,0 +1,99 @@
+package compose
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/docker/compose/v2/pkg/api"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeLogsClient struct {
+	tty     bool
+	content []byte
+	options dockertypes.ContainerLogsOptions
+}
+
+func (c *fakeLogsClient) ContainerInspect(ctx context.Context, id string) (dockertypes.ContainerJSON, error) {
+	return dockertypes.ContainerJSON{Config: &container.Config{Tty: c.tty}}, nil
+}
+
+func (c *fakeLogsClient) ContainerLogs(ctx context.Context, id string, options dockertypes.ContainerLogsOptions) (io.ReadCloser, error) {
+	c.options = options
+	return io.NopCloser(bytes.NewReader(c.content)), nil
+}
+
+func TestReadContainerLogs(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	_, _ = stdcopy.NewStdWriter(&buf, stdcopy.Stdout).Write([]byte("2023-09-28T12:00:00.000000001Z foo\n2023-09-28T12:00:01Z ba"))
+	_, _ = stdcopy.NewStdWriter(&buf, stdcopy.Stderr).Write([]byte("2023-09-28T12:00:02Z err\n"))
+	_, _ = stdcopy.NewStdWriter(&buf, stdcopy.Stdout).Write([]byte("r\nno timestamp"))
+
+	client := &fakeLogsClient{content: buf.Bytes()}
+
+	type line struct {
+		stream LogStream
+		ts     time.Time
+		line   string
+	}
+	var lines []line
+	err := readContainerLogs(
+		context.Background(),
+		client,
+		"id",
+		api.LogOptions{Tail: "10", Follow: true},
+		func(stream LogStream, l string) {
+			ts, l := readLogTimestamp(l)
+			lines = append(lines, line{stream, ts, l})
+		},
+	)
+	assert.NoError(err)
+	assert.True(client.options.Timestamps)
+	assert.True(client.options.Follow)
+	assert.Equal("10", client.options.Tail)
+
+	if diff := cmp.Diff(
+		[]line{
+			{Stdout, time.Date(2023, 9, 28, 12, 0, 0, 1, time.UTC), "foo"},
+			{Stderr, time.Date(2023, 9, 28, 12, 0, 2, 0, time.UTC), "err"},
+			{Stdout, time.Date(2023, 9, 28, 12, 0, 1, 0, time.UTC), "bar"},
+			{Stdout, time.Time{}, "no timestamp"},
+		},
+		lines,
+		cmp.AllowUnexported(line{}),
+	); diff != "" {
+		t.Errorf("not equal. diff = %s", diff)
+	}
+}
+
+func TestLogConsumer(t *testing.T) {
+	project := loadFromString(prefixedServiceNameComposeYaml)
+
+	var records []LogRecord
+	consumer := NewLogConsumer("example_compose", project, func(record LogRecord) {
+		records = append(records, record)
+	})
+	consumer.Register("example_compose-web-admin-1")
+	consumer.Log("example_compose-web-admin-1", "foo")
+	consumer.Err("custom-name", "bar")
+	consumer.Status("custom-name", "exited with code 0")
+
+	if diff := cmp.Diff(
+		[]LogRecord{
+			{Service: "web-admin", Num: 1, Container: "example_compose-web-admin-1", Stream: Stdout, Line: "foo"},
+			{Service: "named", Num: 1, Container: "custom-name", Stream: Stderr, Line: "bar"},
+		},
+		records,
+	); diff != "" {
+		t.Errorf("not equal. diff = %s", diff)
+	}
+}
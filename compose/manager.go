@@ -0,0 +1,435 @@
+package compose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/compose/v2/pkg/compose"
+)
+
+// ErrDependencyFailed is wrapped by errors of projects skipped by bulk operations of Manager
+// since a project they are ordered after failed.
+var ErrDependencyFailed = errors.New("dependency failed")
+
+// ManagerOptions is options for NewManager.
+type ManagerOptions struct {
+	// MaxConcurrency bounds the number of projects which bulk operations operate on at once.
+	// Non-positive means unbounded.
+	MaxConcurrency int
+}
+
+// ProjectResult is the result of a bulk operation on a project.
+type ProjectResult struct {
+	ProjectName string
+	Output      ComposeOutput
+	// Err is nil if the operation succeeded.
+	Err error
+}
+
+// ProjectStatus is containers of a project.
+type ProjectStatus struct {
+	ProjectName string
+	Containers  []api.ContainerSummary
+	// Err is nil if containers are listed.
+	Err error
+}
+
+// DiscoveredProject is a compose project found on the docker daemon.
+type DiscoveredProject struct {
+	Name string
+	// Status is the combined status of containers, e.g. "running(2), exited(1)".
+	Status string
+	// ConfigFiles are paths of compose files labeled to containers of the project.
+	ConfigFiles []string
+	// Managed is true if the project is added to the Manager.
+	Managed bool
+}
+
+type managedProject struct {
+	loader  *LoaderProxy
+	service *ComposeService
+}
+
+// Manager manages multiple compose projects keyed by their names.
+//
+// Bulk operations are run in dependency order among projects.
+// A project depends on another if it uses an external network or volume which the other creates.
+// Start and Up run dependencies first, while Stop and Down run dependents first.
+// If an operation on a project fails, projects ordered after it are skipped with an error wrapping ErrDependencyFailed.
+//
+// Manager is safe to be used from multiple goroutines.
+type Manager struct {
+	mu        sync.RWMutex
+	projects  map[string]managedProject
+	dockerCli command.Cli
+	sem       chan struct{}
+}
+
+// NewManager returns a new Manager. dockerCli is used to discover projects.
+func NewManager(dockerCli command.Cli, options ManagerOptions) *Manager {
+	m := &Manager{
+		projects:  make(map[string]managedProject),
+		dockerCli: dockerCli,
+	}
+	if options.MaxConcurrency > 0 {
+		m.sem = make(chan struct{}, options.MaxConcurrency)
+	}
+	return m
+}
+
+// Add loads a ComposeService by loader and manages it under the project name of loader.
+// It returns an error if a project of the name is already managed.
+func (m *Manager) Add(ctx context.Context, loader *LoaderProxy, ops ...func(p *types.Project) error) (*ComposeService, error) {
+	name := loader.ProjectName()
+	m.mu.RLock()
+	_, ok := m.projects[name]
+	m.mu.RUnlock()
+	if ok {
+		return nil, fmt.Errorf("project %q is already managed", name)
+	}
+
+	service, err := loader.LoadComposeService(ctx, ops...)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.projects[name]; ok {
+		return nil, fmt.Errorf("project %q is already managed", name)
+	}
+	m.projects[name] = managedProject{loader: loader, service: service}
+	return service, nil
+}
+
+// Remove stops managing the project. Its containers are left as they are.
+// It reports whether the project was managed.
+func (m *Manager) Remove(projectName string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.projects[projectName]
+	delete(m.projects, projectName)
+	return ok
+}
+
+// Get returns the loader and the service of the project.
+func (m *Manager) Get(projectName string) (*LoaderProxy, *ComposeService, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	p, ok := m.projects[projectName]
+	return p.loader, p.service, ok
+}
+
+// ProjectNames returns names of managed projects in sorted order.
+func (m *Manager) ProjectNames() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return sortedKeys(m.projects)
+}
+
+// Discover lists compose projects which have containers on the docker daemon, including stopped ones.
+// Projects are found by api.ProjectLabel labeled to containers, as `compose ls --all` does.
+func (m *Manager) Discover(ctx context.Context) ([]DiscoveredProject, error) {
+	stacks, err := compose.NewComposeService(m.dockerCli).List(ctx, api.ListOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	discovered := make([]DiscoveredProject, len(stacks))
+	for i, stack := range stacks {
+		var files []string
+		if stack.ConfigFiles != "" {
+			files = strings.Split(stack.ConfigFiles, ",")
+		}
+		_, managed := m.projects[stack.Name]
+		discovered[i] = DiscoveredProject{
+			Name:        stack.Name,
+			Status:      stack.Status,
+			ConfigFiles: files,
+			Managed:     managed,
+		}
+	}
+	return discovered, nil
+}
+
+// UpAll calls Up of all managed projects, dependencies first.
+// options.Create.Services and options.Start.Services must be empty, and options.Start.Project is ignored.
+func (m *Manager) UpAll(ctx context.Context, options api.UpOptions) ([]ProjectResult, error) {
+	if len(options.Create.Services) > 0 || len(options.Start.Services) > 0 {
+		return nil, fmt.Errorf("services can not be specified for all projects")
+	}
+	options.Start.Project = nil
+	return m.runOrdered(ctx, false, func(ctx context.Context, s *ComposeService) (ComposeOutput, error) {
+		return s.Up(ctx, options)
+	})
+}
+
+// StartAll calls Start of all managed projects, dependencies first.
+// options.Services must be empty, and options.Project is ignored.
+func (m *Manager) StartAll(ctx context.Context, options api.StartOptions) ([]ProjectResult, error) {
+	if len(options.Services) > 0 {
+		return nil, fmt.Errorf("services can not be specified for all projects")
+	}
+	options.Project = nil
+	return m.runOrdered(ctx, false, func(ctx context.Context, s *ComposeService) (ComposeOutput, error) {
+		return s.Start(ctx, options)
+	})
+}
+
+// StopAll calls Stop of all managed projects, dependents first.
+// options.Services must be empty, and options.Project is ignored.
+func (m *Manager) StopAll(ctx context.Context, options api.StopOptions) ([]ProjectResult, error) {
+	if len(options.Services) > 0 {
+		return nil, fmt.Errorf("services can not be specified for all projects")
+	}
+	options.Project = nil
+	return m.runOrdered(ctx, true, func(ctx context.Context, s *ComposeService) (ComposeOutput, error) {
+		return s.Stop(ctx, options)
+	})
+}
+
+// DownAll calls Down of all managed projects, dependents first.
+// options.Services must be empty, and options.Project is ignored.
+func (m *Manager) DownAll(ctx context.Context, options api.DownOptions) ([]ProjectResult, error) {
+	if len(options.Services) > 0 {
+		return nil, fmt.Errorf("services can not be specified for all projects")
+	}
+	options.Project = nil
+	return m.runOrdered(ctx, true, func(ctx context.Context, s *ComposeService) (ComposeOutput, error) {
+		return s.Down(ctx, options)
+	})
+}
+
+// StatusAll lists containers of all managed projects, including stopped ones.
+// The returned statuses are sorted by project name.
+// The returned error is the joined errors of them.
+func (m *Manager) StatusAll(ctx context.Context) ([]ProjectStatus, error) {
+	projects := m.snapshot()
+	names := sortedKeys(projects)
+
+	statuses := make([]ProjectStatus, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			statuses[i].ProjectName = name
+			if err := m.acquire(ctx); err != nil {
+				statuses[i].Err = err
+				return
+			}
+			defer m.release()
+			statuses[i].Containers, statuses[i].Err = projects[name].service.Ps(ctx, api.PsOptions{All: true})
+		}(i, name)
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, status := range statuses {
+		if status.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", status.ProjectName, status.Err))
+		}
+	}
+	return statuses, errors.Join(errs...)
+}
+
+func (m *Manager) snapshot() map[string]managedProject {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	projects := make(map[string]managedProject, len(m.projects))
+	for name, p := range m.projects {
+		projects[name] = p
+	}
+	return projects
+}
+
+func (m *Manager) acquire(ctx context.Context) error {
+	if m.sem == nil {
+		return ctx.Err()
+	}
+	select {
+	case m.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *Manager) release() {
+	if m.sem != nil {
+		<-m.sem
+	}
+}
+
+// runOrdered runs fn for all managed projects in dependency order, or in reverse order if reverse is true.
+// Projects which do not depend on each other run concurrently up to the bound of m.
+// The returned results are in the order projects are sorted, and the returned error is the joined errors of them.
+func (m *Manager) runOrdered(
+	ctx context.Context,
+	reverse bool,
+	fn func(ctx context.Context, s *ComposeService) (ComposeOutput, error),
+) ([]ProjectResult, error) {
+	projects := m.snapshot()
+	loaded := make(map[string]*types.Project, len(projects))
+	for name, p := range projects {
+		loaded[name] = p.service.snapshot()
+	}
+	deps := projectDependencies(loaded)
+	if reverse {
+		deps = reverseDependencies(deps)
+	}
+	order, err := sortProjects(deps)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ProjectResult, len(order))
+	done := make(map[string]chan struct{}, len(order))
+	for _, name := range order {
+		done[name] = make(chan struct{})
+	}
+	failed := make(map[string]bool, len(order))
+	var mu sync.Mutex
+
+	var wg sync.WaitGroup
+	for i, name := range order {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			defer close(done[name])
+			results[i].ProjectName = name
+			for _, dep := range deps[name] {
+				<-done[dep]
+				mu.Lock()
+				depFailed := failed[dep]
+				mu.Unlock()
+				if depFailed {
+					results[i].Err = fmt.Errorf("%w: %s", ErrDependencyFailed, dep)
+					break
+				}
+			}
+			if results[i].Err == nil {
+				if err := m.acquire(ctx); err != nil {
+					results[i].Err = err
+				} else {
+					results[i].Output, results[i].Err = fn(ctx, projects[name].service)
+					m.release()
+				}
+			}
+			if results[i].Err != nil {
+				mu.Lock()
+				failed[name] = true
+				mu.Unlock()
+			}
+		}(i, name)
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.ProjectName, r.Err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// projectDependencies maps each project name to names of projects it depends on.
+// A project depends on another if it uses an external network or volume whose name is one the other creates.
+func projectDependencies(projects map[string]*types.Project) map[string][]string {
+	networkOwners := make(map[string]string)
+	volumeOwners := make(map[string]string)
+	for name, project := range projects {
+		for _, network := range project.Networks {
+			if !network.External.External {
+				networkOwners[network.Name] = name
+			}
+		}
+		for _, volume := range project.Volumes {
+			if !volume.External.External {
+				volumeOwners[volume.Name] = name
+			}
+		}
+	}
+
+	deps := make(map[string][]string, len(projects))
+	for name, project := range projects {
+		deps[name] = nil
+		add := func(owner string, ok bool) {
+			if ok && owner != name && !slices.Contains(deps[name], owner) {
+				deps[name] = append(deps[name], owner)
+			}
+		}
+		for _, network := range project.Networks {
+			if network.External.External {
+				owner, ok := networkOwners[network.Name]
+				add(owner, ok)
+			}
+		}
+		for _, volume := range project.Volumes {
+			if volume.External.External {
+				owner, ok := volumeOwners[volume.Name]
+				add(owner, ok)
+			}
+		}
+		slices.Sort(deps[name])
+	}
+	return deps
+}
+
+func reverseDependencies(deps map[string][]string) map[string][]string {
+	reversed := make(map[string][]string, len(deps))
+	for name := range deps {
+		reversed[name] = nil
+	}
+	for _, name := range sortedKeys(deps) {
+		for _, dep := range deps[name] {
+			reversed[dep] = append(reversed[dep], name)
+		}
+	}
+	return reversed
+}
+
+// sortProjects sorts project names so that each comes after its dependencies, ties broken by name.
+// It returns an error if dependencies are cyclic.
+func sortProjects(deps map[string][]string) ([]string, error) {
+	var (
+		order    []string
+		visiting = make(map[string]bool)
+		visited  = make(map[string]bool)
+		visit    func(name string) error
+	)
+	visit = func(name string) error {
+		if visited[name] {
+			return nil
+		}
+		if visiting[name] {
+			return fmt.Errorf("projects have cyclic dependency through %q", name)
+		}
+		visiting[name] = true
+		for _, dep := range deps[name] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		visiting[name] = false
+		visited[name] = true
+		order = append(order, name)
+		return nil
+	}
+	for _, name := range sortedKeys(deps) {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
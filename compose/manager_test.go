@@ -0,0 +1,173 @@
+package compose
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var managerComposeYamls = map[string]string{
+	// proxy creates the network which app and admin join.
+	"proxy": `services:
+  proxy:
+    image: ubuntu:jammy-20230624
+networks:
+  default:
+    name: shared-proxy
+`,
+	"app": `services:
+  app:
+    image: ubuntu:jammy-20230624
+    networks: [proxy]
+    volumes: [data:/data]
+networks:
+  proxy:
+    name: shared-proxy
+    external: true
+volumes:
+  data:
+    name: app-data
+`,
+	"admin": `services:
+  admin:
+    image: ubuntu:jammy-20230624
+    networks: [proxy]
+    volumes: [data:/data]
+networks:
+  proxy:
+    name: shared-proxy
+    external: true
+volumes:
+  data:
+    name: app-data
+    external: true
+`,
+	"standalone": `services:
+  standalone:
+    image: ubuntu:jammy-20230624
+`,
+}
+
+func newManagerForTest(t *testing.T, options ManagerOptions) *Manager {
+	t.Helper()
+	m := NewManager(loaderBase.DockerCli(), options)
+	for _, name := range sortedKeys(managerComposeYamls) {
+		loader, err := NewLoaderProxy(
+			name,
+			types.ConfigDetails{
+				WorkingDir: "./testdata",
+				ConfigFiles: []types.ConfigFile{
+					{Filename: "./testdata/" + name + ".yml", Content: []byte(managerComposeYamls[name])},
+				},
+				Environment: types.NewMapping(os.Environ()),
+			},
+			nil,
+			nil,
+		)
+		require.NoError(t, err)
+		_, err = m.Add(context.Background(), loader)
+		require.NoError(t, err)
+	}
+	return m
+}
+
+func TestManager_projects(t *testing.T) {
+	assert := assert.New(t)
+	m := newManagerForTest(t, ManagerOptions{})
+
+	assert.Equal([]string{"admin", "app", "proxy", "standalone"}, m.ProjectNames())
+
+	loader, service, ok := m.Get("app")
+	assert.True(ok)
+	assert.Equal("app", loader.ProjectName())
+	assert.Equal("app", service.projectName)
+
+	_, err := m.Add(context.Background(), loader)
+	assert.Error(err)
+
+	assert.True(m.Remove("app"))
+	assert.False(m.Remove("app"))
+	_, _, ok = m.Get("app")
+	assert.False(ok)
+}
+
+func TestProjectDependencies(t *testing.T) {
+	assert := assert.New(t)
+	m := newManagerForTest(t, ManagerOptions{})
+
+	projects := make(map[string]*types.Project)
+	for name, p := range m.snapshot() {
+		projects[name] = p.service.snapshot()
+	}
+	deps := projectDependencies(projects)
+	assert.Equal(map[string][]string{
+		"admin":      {"app", "proxy"},
+		"app":        {"proxy"},
+		"proxy":      nil,
+		"standalone": nil,
+	}, deps)
+
+	order, err := sortProjects(deps)
+	assert.NoError(err)
+	assert.Equal([]string{"proxy", "app", "admin", "standalone"}, order)
+
+	order, err = sortProjects(reverseDependencies(deps))
+	assert.NoError(err)
+	assert.Equal([]string{"admin", "app", "proxy", "standalone"}, order)
+
+	_, err = sortProjects(map[string][]string{"a": {"b"}, "b": {"a"}})
+	assert.Error(err)
+}
+
+func TestManager_runOrdered(t *testing.T) {
+	assert := assert.New(t)
+	m := newManagerForTest(t, ManagerOptions{MaxConcurrency: 2})
+
+	run := func(reverse bool, fail string) ([]string, []ProjectResult, error) {
+		var (
+			mu  sync.Mutex
+			ran []string
+		)
+		results, err := m.runOrdered(context.Background(), reverse, func(ctx context.Context, s *ComposeService) (ComposeOutput, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			ran = append(ran, s.projectName)
+			if s.projectName == fail {
+				return ComposeOutput{}, errors.New("failed")
+			}
+			return ComposeOutput{}, nil
+		})
+		return ran, results, err
+	}
+
+	ran, _, err := run(false, "")
+	assert.NoError(err)
+	assert.Less(slices.Index(ran, "proxy"), slices.Index(ran, "app"))
+	assert.Less(slices.Index(ran, "app"), slices.Index(ran, "admin"))
+
+	ran, _, err = run(true, "")
+	assert.NoError(err)
+	assert.Less(slices.Index(ran, "admin"), slices.Index(ran, "app"))
+	assert.Less(slices.Index(ran, "app"), slices.Index(ran, "proxy"))
+
+	ran, results, err := run(false, "proxy")
+	assert.Error(err)
+	assert.ElementsMatch([]string{"proxy", "standalone"}, ran)
+	for _, r := range results {
+		switch r.ProjectName {
+		case "proxy":
+			assert.EqualError(r.Err, "failed")
+		case "app", "admin":
+			assert.ErrorIs(r.Err, ErrDependencyFailed)
+		default:
+			assert.NoError(r.Err)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"unicode"
 
 	"github.com/compose-spec/compose-go/types"
+	"github.com/docker/go-units"
 )
 
 const (
@@ -21,6 +22,11 @@ const (
 	Container ResourceType = "Container"
 	Volume    ResourceType = "Volume"
 	Network   ResourceType = "Network"
+	// Service is the resource type of per-service lines emitted by image operations, e.g. pull, push and build.
+	// compose prints them with or without "Service" prefix.
+	Service ResourceType = "Service"
+	// Layer is the resource type of image layer progress lines emitted while pulling or pushing images.
+	Layer ResourceType = "Layer"
 )
 
 // Copied from https://github.com/docker/compose/blob/19bbb12fac83e19f3ef888722cbb32825b4088e6/pkg/progress/event.go
@@ -47,12 +53,51 @@ const (
 	Skipped    StateType = "Skipped" // depends_on is set, required is false and dependency service is not running nor present.
 	Recreate   StateType = "Recreate"
 	Recreated  StateType = "Recreated"
+	Paused     StateType = "Paused"
+	Unpaused   StateType = "Unpaused"
+	// image operations.
+	Pulling     StateType = "Pulling"
+	Pulled      StateType = "Pulled"
+	Pushing     StateType = "Pushing"
+	Pushed      StateType = "Pushed"
+	Building    StateType = "Building"
+	Built       StateType = "Built"
+	Warning     StateType = "Warning"
+	Preparing   StateType = "Preparing"
+	Downloading StateType = "Downloading"
+	Extracting  StateType = "Extracting"
+	// Copied from https://github.com/docker/compose/blob/v2.22.0/pkg/compose/pull.go
+	PullingFsLayer    StateType = "Pulling fs layer"
+	DownloadComplete  StateType = "Download complete"
+	VerifyingChecksum StateType = "Verifying Checksum"
+	AlreadyExists     StateType = "Already exists"
+	PullComplete      StateType = "Pull complete"
+	// Printed by the daemon while pushing.
+	LayerAlreadyExists StateType = "Layer already exists"
 )
 
+// states is ordered so that a state comes before any other state which is a prefix of it.
 var states = []StateType{
+	LayerAlreadyExists,
+	VerifyingChecksum,
+	DownloadComplete,
+	PullingFsLayer,
+	AlreadyExists,
+	PullComplete,
+	Downloading,
+	Extracting,
+	Preparing,
+	Building,
+	Pulling,
+	Pushing,
+	Warning,
+	Pulled,
+	Pushed,
+	Built,
 	Restarting,
 	Restarted,
 	Recreated,
+	Unpaused,
 	Creating,
 	Starting,
 	Recreate,
@@ -65,6 +110,7 @@ var states = []StateType{
 	Killing,
 	Removed,
 	Skipped,
+	Paused,
 	Waiting,
 	Started,
 	Exited,
@@ -73,14 +119,43 @@ var states = []StateType{
 }
 
 type ComposeOutput struct {
+	// Resource is the last line for each resource keyed by "Type:Name".
+	// Since replica number is not part of the key, replicas of a service overwrite each other.
+	// Use Replicas to distinguish them.
 	Resource map[string]ComposeOutputLine
+	// Replicas is the last line for each resource, including the replica number.
+	Replicas map[ResourceKey]ComposeOutputLine
+	// Events is progress events in order of emission.
+	Events   []ProgressEvent
 	Out, Err string
 }
 
+// ResourceKey identifies a resource in ComposeOutput.
+// Num is the replica number for containers, zero for other resources.
+type ResourceKey struct {
+	ResourceType ResourceType
+	Name         string
+	Num          int
+}
+
+func (k ResourceKey) String() string {
+	if k.ResourceType == Container {
+		return string(k.ResourceType) + ":" + k.Name + "-" + strconv.Itoa(k.Num)
+	}
+	return string(k.ResourceType) + ":" + k.Name
+}
+
+// ParseOutput parses human readable progress texts written by compose.
+// This is a fallback for outputs captured outside of ComposeService,
+// which populates o from events decoded as they are emitted instead. See ProgressEvent.
+// Time of each event is left zero.
 func (o *ComposeOutput) ParseOutput(stdout, stderr string, projectName string, project *types.Project, isDryRunMode bool) {
 	if o.Resource == nil {
 		o.Resource = make(map[string]ComposeOutputLine)
 	}
+	if o.Replicas == nil {
+		o.Replicas = make(map[ResourceKey]ComposeOutputLine)
+	}
 	o.Out = stdout
 	o.Err = stderr
 
@@ -91,28 +166,129 @@ func (o *ComposeOutput) ParseOutput(stdout, stderr string, projectName string, p
 			if line == "" {
 				continue
 			}
-			decoded, err := DecodeComposeOutputLine(line, projectName, project, isDryRunMode)
+			event, err := DecodeProgressEvent(line, projectName, project, isDryRunMode)
 			if err != nil {
 				continue
 			}
-			o.Resource[string(decoded.ResourceType)+":"+decoded.Name] = decoded
+			o.AddEvent(event)
+		}
+	}
+}
+
+// AddEvent appends event to o.Events.
+// If event has known StateType, it also updates o.Resource.
+func (o *ComposeOutput) AddEvent(event ProgressEvent) {
+	if o.Resource == nil {
+		o.Resource = make(map[string]ComposeOutputLine)
+	}
+	if o.Replicas == nil {
+		o.Replicas = make(map[ResourceKey]ComposeOutputLine)
+	}
+	o.Events = append(o.Events, event)
+	if event.StateType != "" {
+		o.Resource[string(event.ResourceType)+":"+event.Name] = event.ComposeOutputLine
+		o.Replicas[event.Key()] = event.ComposeOutputLine
+	}
+}
+
+// Failed returns last lines of resources which ended up in Error state, sorted by their keys.
+func (o ComposeOutput) Failed() []ComposeOutputLine {
+	var failed []ComposeOutputLine
+	for _, line := range o.Replicas {
+		if line.StateType == Error {
+			failed = append(failed, line)
+		}
+	}
+	sortLines(failed)
+	return failed
+}
+
+// Transitions returns events of resources named name in order of emission.
+// name is a service name for containers, or a name of the resource for others.
+// Events of every replica are included; use Num to tell them apart.
+func (o ComposeOutput) Transitions(name string) []ProgressEvent {
+	var transitions []ProgressEvent
+	for _, event := range o.Events {
+		if event.Name == name {
+			transitions = append(transitions, event)
 		}
 	}
+	return transitions
+}
+
+// ByService groups last lines of containers and image operations by service name.
+// Lines are sorted by resource type then replica number.
+func (o ComposeOutput) ByService() map[string][]ComposeOutputLine {
+	grouped := make(map[string][]ComposeOutputLine)
+	for _, line := range o.Replicas {
+		switch line.ResourceType {
+		case Container, Service:
+			grouped[line.Name] = append(grouped[line.Name], line)
+		}
+	}
+	for _, lines := range grouped {
+		sortLines(lines)
+	}
+	return grouped
+}
+
+func sortLines(lines []ComposeOutputLine) {
+	sort.Slice(lines, func(i, j int) bool {
+		l, r := lines[i], lines[j]
+		if l.ResourceType != r.ResourceType {
+			return l.ResourceType < r.ResourceType
+		}
+		if l.Name != r.Name {
+			return l.Name < r.Name
+		}
+		return l.Num < r.Num
+	})
 }
 
 type ComposeOutputLine struct {
-	Name         string
-	Num          int
+	Name string
+	Num  int
+	// Parent is the service name which the layer belongs to.
+	// It is only set for Layer lines when compose prints it, i.e. while pushing.
+	Parent       string
 	ResourceType ResourceType
 	StateType    StateType
 	Desc         string
-	DryRunMode   bool
+	// Current and Total are bytes read from layer progress.
+	// Those are approximate since compose prints them in human readable form.
+	Current, Total int64
+	DryRunMode     bool
+}
+
+// Key returns the key of the resource l is describing.
+func (l ComposeOutputLine) Key() ResourceKey {
+	return ResourceKey{
+		ResourceType: l.ResourceType,
+		Name:         l.Name,
+		Num:          l.Num,
+	}
 }
 
 func DecodeComposeOutputLine(line string, projectName string, project *types.Project, isDryRunMode bool) (ComposeOutputLine, error) {
-	orgLine := line
+	decoded, _, _, err := decodeLine(line, projectName, project, isDryRunMode)
+	if err != nil {
+		return ComposeOutputLine{}, err
+	}
+	if decoded.StateType == "" {
+		return ComposeOutputLine{}, fmt.Errorf("unknown state. input = %s", line)
+	}
+	return decoded, nil
+}
 
-	var decoded ComposeOutputLine
+// decodeLine decodes line into ComposeOutputLine.
+// Unlike DecodeComposeOutputLine, it does not fail on an unknown state as long as the resource is known.
+// In that case StateType of returned decoded is empty.
+// id is the resource id compose has printed and text is rest of line after id.
+func decodeLine(line string, projectName string, project *types.Project, isDryRunMode bool) (decoded ComposeOutputLine, id string, text string, err error) {
+	if project == nil {
+		return ComposeOutputLine{}, "", "", fmt.Errorf("project is nil")
+	}
+	orgLine := line
 
 	line = strings.TrimLeftFunc(line, unicode.IsSpace)
 
@@ -121,21 +297,37 @@ func DecodeComposeOutputLine(line string, projectName string, project *types.Pro
 	if found || isDryRunMode {
 		decoded.DryRunMode = true
 	}
+	line = strings.TrimLeftFunc(line, unicode.IsSpace)
+	idStart := line
 
 	decoded.ResourceType, line = readResourceType(line)
-	if decoded.ResourceType == "" {
-		return ComposeOutputLine{}, fmt.Errorf("unknown resource type. input = %s", orgLine)
+	switch decoded.ResourceType {
+	case "":
+		decoded.ResourceType, decoded.Name, decoded.Parent, line = readImageResource(line, project)
+		if decoded.ResourceType == "" {
+			return ComposeOutputLine{}, "", "", fmt.Errorf("unknown resource type. input = %s", orgLine)
+		}
+	case Service:
+		decoded.Name, line = readServiceName(line, project)
+	default:
+		decoded.Name, decoded.Num, line = readResourceName(line, projectName, project, decoded.ResourceType)
 	}
-	decoded.Name, decoded.Num, line = readResourceName(line, projectName, project, decoded.ResourceType)
 	if decoded.Name == "" {
-		return ComposeOutputLine{}, fmt.Errorf("unknown resource name. input = %s", orgLine)
+		return ComposeOutputLine{}, "", "", fmt.Errorf("unknown resource name. input = %s", orgLine)
 	}
+	id = strings.TrimSpace(idStart[:len(idStart)-len(line)])
+	text = strings.TrimSpace(line)
+
 	decoded.StateType, decoded.Desc = readState(line)
 	if decoded.StateType == "" {
-		return ComposeOutputLine{}, fmt.Errorf("unknown state. input = %s", orgLine)
+		decoded.Desc = text
+	}
+	decoded.Desc = strings.TrimSpace(decoded.Desc)
+	if decoded.ResourceType == Layer {
+		decoded.Current, decoded.Total = readLayerProgress(decoded.Desc)
 	}
 
-	return decoded, nil
+	return decoded, id, text, nil
 }
 
 func readResourceType(s string) (resource ResourceType, rest string) {
@@ -151,13 +343,17 @@ func readResourceType(s string) (resource ResourceType, rest string) {
 	case strings.HasPrefix(s, string(Network)):
 		rest, _ = strings.CutPrefix(s, string(Network))
 		return Network, rest
+	case strings.HasPrefix(s, string(Service)+" "):
+		rest, _ = strings.CutPrefix(s, string(Service))
+		return Service, rest
 	}
 	return "", s
 }
 
 func readResourceName(s string, projectName string, project *types.Project, resourceTy ResourceType) (service string, num int, rest string) {
 	s = strings.TrimLeftFunc(s, unicode.IsSpace)
-	if s[0] == '"' {
+	orgS := s
+	if s != "" && s[0] == '"' {
 		s = s[1:]
 	}
 	// I don't know why. But volume name is printed vis fmt.*printf variants and it uses the %q formatter.
@@ -171,52 +367,65 @@ func readResourceName(s string, projectName string, project *types.Project, reso
 
 	switch resourceTy {
 	case Container:
-		for _, serviceCfg := range project.Services {
-			if strings.HasPrefix(s, serviceCfg.Name) {
-				rest, _ := strings.CutPrefix(s, serviceCfg.Name)
-				if rest[0] != '-' {
-					continue
-				}
-				rest = rest[1:]
-				var i int
-				for i = 0; i < len(rest); i++ {
-					if rest[i] == ' ' {
-						break
-					}
-				}
-				numStr := rest[0:i]
-				num, _ := strconv.ParseInt(numStr, 10, 64)
-				rest = rest[i:]
-				return serviceCfg.Name, int(num), rest
-			}
+		// Container names never contain spaces.
+		containerName, rest, _ := strings.Cut(orgS, " ")
+		if service, num, ok := resolveContainerName(containerName, projectName, project); ok {
+			return service, num, rest
 		}
 	case Network:
 		networkCfg := project.NetworkNames()
 		sort.Strings(networkCfg)
 		for i := len(networkCfg) - 1; i >= 0; i-- {
-			if strings.HasPrefix(s, networkCfg[i]) && (s[len(networkCfg[i])] == '"' || s[len(networkCfg[i])] == ' ') {
-				s, _ = strings.CutPrefix(s, networkCfg[i])
-				if s[0] == '"' {
-					s = s[1:]
-				}
-				return networkCfg[i], 0, s
+			if rest, found := cutResourceName(s, networkCfg[i]); found {
+				return networkCfg[i], 0, rest
 			}
-
 		}
 	case Volume:
 		for volumeName := range project.Volumes {
-			if strings.HasPrefix(s, volumeName) && (s[len(volumeName)] == '"' || s[len(volumeName)] == ' ') {
-				s, _ = strings.CutPrefix(s, volumeName)
-				if s[0] == '"' {
-					s = s[1:]
-				}
-				return volumeName, 0, s
+			if rest, found := cutResourceName(s, volumeName); found {
+				return volumeName, 0, rest
 			}
 		}
 	}
 	return "", 0, s
 }
 
+// resolveContainerName resolves service name and replica number from containerName.
+func resolveContainerName(containerName string, projectName string, project *types.Project) (service string, num int, ok bool) {
+	for _, serviceCfg := range project.AllServices() {
+		if serviceCfg.ContainerName != "" && containerName == serviceCfg.ContainerName {
+			return serviceCfg.Name, 1, true
+		}
+		// Checking the whole name is needed since a service name can be a prefix of another.
+		// Both "-" and legacy "_" are used as separator.
+		for _, sep := range []string{"-", "_"} {
+			numStr, found := strings.CutPrefix(containerName, projectName+sep+serviceCfg.Name+sep)
+			if !found {
+				continue
+			}
+			if num, err := strconv.Atoi(numStr); err == nil {
+				return serviceCfg.Name, num, true
+			}
+			// one-off containers are named projectName-serviceName-run-slug and have no replica number.
+			if slug, found := strings.CutPrefix(numStr, "run"+sep); found && isTruncatedID(slug) {
+				return serviceCfg.Name, 0, true
+			}
+		}
+	}
+	return "", 0, false
+}
+
+// cutResourceName cuts name from s if s starts with name followed by a quotation, a space or nothing.
+// A trailing quotation is also removed.
+func cutResourceName(s string, name string) (rest string, found bool) {
+	rest, found = strings.CutPrefix(s, name)
+	if !found || (rest != "" && rest[0] != '"' && rest[0] != ' ') {
+		return s, false
+	}
+	rest, _ = strings.CutPrefix(rest, "\"")
+	return rest, true
+}
+
 func readState(s string) (state StateType, rest string) {
 	s = strings.TrimLeftFunc(s, unicode.IsSpace)
 	for _, ss := range states {
@@ -227,3 +436,93 @@ func readState(s string) (state StateType, rest string) {
 	}
 	return "", s
 }
+
+// readImageResource reads lines emitted by image operations, which are not prefixed by the resource type.
+//
+// Those are one of
+//   - "Pushing " + serviceName + ": " + layerID
+//   - serviceName
+//   - layerID
+func readImageResource(s string, project *types.Project) (resource ResourceType, name string, parent string, rest string) {
+	if after, found := strings.CutPrefix(s, "Pushing "); found {
+		for _, serviceName := range sortedServiceNames(project) {
+			layer, found := strings.CutPrefix(after, serviceName+": ")
+			if !found {
+				continue
+			}
+			id, rest, _ := strings.Cut(layer, " ")
+			if !isTruncatedID(id) {
+				break
+			}
+			return Layer, id, serviceName, rest
+		}
+	}
+
+	name, rest = readServiceName(s, project)
+	if name != "" {
+		return Service, name, "", rest
+	}
+
+	id, rest, _ := strings.Cut(s, " ")
+	if isTruncatedID(id) {
+		return Layer, id, "", rest
+	}
+	return "", "", "", s
+}
+
+// readServiceName reads service name from s.
+// The longest match wins so that a service name which is a prefix of another would not be mistakenly matched.
+func readServiceName(s string, project *types.Project) (service string, rest string) {
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	for _, serviceName := range sortedServiceNames(project) {
+		rest, found := strings.CutPrefix(s, serviceName)
+		if found && (rest == "" || rest[0] == ' ') {
+			return serviceName, rest
+		}
+	}
+	return "", s
+}
+
+// sortedServiceNames returns every service name in project sorted in descending order,
+// so that longer names come before their prefixes.
+func sortedServiceNames(project *types.Project) []string {
+	var names []string
+	for _, s := range project.AllServices() {
+		names = append(names, s.Name)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	return names
+}
+
+// isTruncatedID reports whether s is a truncated id of a layer, a container or a slug,
+// which consists of 12 lower hex digits.
+func isTruncatedID(s string) bool {
+	if len(s) != 12 {
+		return false
+	}
+	for _, r := range s {
+		if !('0' <= r && r <= '9') && !('a' <= r && r <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// readLayerProgress reads bytes from s, which is formatted by (*jsonmessage.JSONProgress).String.
+// It looks like "[==>    ]  1.2MB/3.4MB 10s" or "1.2MB".
+func readLayerProgress(s string) (current, total int64) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "[") {
+		_, s, _ = strings.Cut(s, "]")
+		s = strings.TrimSpace(s)
+	}
+	numbers, _, _ := strings.Cut(s, " ")
+	currentStr, totalStr, _ := strings.Cut(numbers, "/")
+	if parsed, err := units.FromHumanSize(currentStr); err == nil {
+		current = parsed
+	}
+	if parsed, err := units.FromHumanSize(totalStr); err == nil {
+		total = parsed
+	}
+	return current, total
+}
@@ -67,6 +67,26 @@ var (
 		{ResourceType: Network, Name: "sample network", StateType: Removing},
 		{ResourceType: Network, Name: "sample network", StateType: Removed},
 	}
+	pullOutput = []ComposeOutputLine{
+		{ResourceType: Service, Name: "additional", StateType: Skipped, Desc: "- Image is already present locally"},
+		{ResourceType: Service, Name: "sample_service", StateType: Pulling},
+		{ResourceType: Layer, Name: "44ba2882f8eb", StateType: PullingFsLayer},
+		{
+			ResourceType: Layer, Name: "44ba2882f8eb", StateType: Downloading,
+			Desc:    "[>                                                  ]  302.3kB/29.54MB",
+			Current: 302300, Total: 29540000,
+		},
+		{ResourceType: Layer, Name: "167b8a53ca45", StateType: AlreadyExists},
+		{ResourceType: Layer, Name: "44ba2882f8eb", StateType: VerifyingChecksum},
+		{ResourceType: Layer, Name: "44ba2882f8eb", StateType: DownloadComplete},
+		{
+			ResourceType: Layer, Name: "44ba2882f8eb", StateType: Extracting,
+			Desc:    "[==================================================>]  29.54MB/29.54MB",
+			Current: 29540000, Total: 29540000,
+		},
+		{ResourceType: Layer, Name: "44ba2882f8eb", StateType: PullComplete},
+		{ResourceType: Service, Name: "sample_service", StateType: Pulled},
+	}
 
 	createDryRunOutputResourceMap = map[string]ComposeOutputLine{
 		"Network:sample network":   {DryRunMode: true, ResourceType: Network, Name: "sample network", StateType: Created},
@@ -123,6 +143,10 @@ func TestOutputString(t *testing.T) {
 			lines:    down,
 			expected: downOutput,
 		},
+		{
+			lines:    pull,
+			expected: pullOutput,
+		},
 		{
 			lines:     nonexistentComposeYml,
 			shouldErr: true,
@@ -149,6 +173,44 @@ func TestOutputString(t *testing.T) {
 		}
 	}
 
+	for _, tc := range []struct {
+		line     string
+		expected ComposeOutputLine
+	}{
+		{
+			line: " Pushing sample_service: 44ba2882f8eb Pushing [=====>      ]  3.2MB/29.54MB",
+			expected: ComposeOutputLine{
+				ResourceType: Layer, Name: "44ba2882f8eb", Parent: "sample_service", StateType: Pushing,
+				Desc:    "[=====>      ]  3.2MB/29.54MB",
+				Current: 3200000, Total: 29540000,
+			},
+		},
+		{
+			line: " Pushing sample_service: 167b8a53ca45 Layer already exists ",
+			expected: ComposeOutputLine{
+				ResourceType: Layer, Name: "167b8a53ca45", Parent: "sample_service", StateType: LayerAlreadyExists,
+			},
+		},
+		{
+			line:     "Service sample_service  Built",
+			expected: ComposeOutputLine{ResourceType: Service, Name: "sample_service", StateType: Built},
+		},
+		{
+			line:     " Container testdata-sample_service-1  Paused",
+			expected: ComposeOutputLine{ResourceType: Container, Name: "sample_service", Num: 1, StateType: Paused},
+		},
+		{
+			line:     " Container testdata-sample_service-1  Unpaused",
+			expected: ComposeOutputLine{ResourceType: Container, Name: "sample_service", Num: 1, StateType: Unpaused},
+		},
+	} {
+		decoded, err := DecodeComposeOutputLine(tc.line, "testdata", project, false)
+		assert.NoError(err)
+		if diff := cmp.Diff(tc.expected, decoded); diff != "" {
+			t.Errorf("not equal. diff =%s", diff)
+		}
+	}
+
 	var out ComposeOutput
 	out.ParseOutput("", createDryRunTxt, "testdata", project, false)
 
@@ -186,3 +248,82 @@ var down string
 
 //go:embed  testdata/08_nonexistent_compose_yml.txt
 var nonexistentComposeYml string
+
+//go:embed  testdata/09_pull.txt
+var pull string
+
+func TestComposeOutput_history(t *testing.T) {
+	assert := assert.New(t)
+
+	project := loadFromString(prefixedServiceNameComposeYaml)
+
+	var out ComposeOutput
+	out.ParseOutput(
+		"",
+		strings.Join([]string{
+			" Container example_compose-web-1  Recreate",
+			" Container example_compose-web-2  Recreate",
+			" Container example_compose-web-1  Recreated",
+			" Container example_compose-web-2  Error",
+			" Container example_compose-web-admin-1  Starting",
+			" Container example_compose-web-1  Starting",
+			" Container example_compose-web-1  Started",
+			" Container example_compose-web-admin-1  Started",
+		}, "\n"),
+		"example_compose",
+		project,
+		false,
+	)
+
+	assert.Len(out.Events, 8)
+	assert.Len(out.Resource, 2)
+	assert.Len(out.Replicas, 3)
+
+	if diff := cmp.Diff(
+		[]ComposeOutputLine{{ResourceType: Container, Name: "web", Num: 2, StateType: Error}},
+		out.Failed(),
+	); diff != "" {
+		t.Errorf("not equal. diff = %s", diff)
+	}
+
+	var transitions []string
+	for _, e := range out.Transitions("web") {
+		transitions = append(transitions, e.Key().String()+" "+string(e.StateType))
+	}
+	if diff := cmp.Diff(
+		[]string{
+			"Container:web-1 Recreate",
+			"Container:web-2 Recreate",
+			"Container:web-1 Recreated",
+			"Container:web-2 Error",
+			"Container:web-1 Starting",
+			"Container:web-1 Started",
+		},
+		transitions,
+	); diff != "" {
+		t.Errorf("not equal. diff = %s", diff)
+	}
+
+	if diff := cmp.Diff(
+		map[string][]ComposeOutputLine{
+			"web": {
+				{ResourceType: Container, Name: "web", Num: 1, StateType: Started},
+				{ResourceType: Container, Name: "web", Num: 2, StateType: Error},
+			},
+			"web-admin": {
+				{ResourceType: Container, Name: "web-admin", Num: 1, StateType: Started},
+			},
+		},
+		out.ByService(),
+	); diff != "" {
+		t.Errorf("not equal. diff = %s", diff)
+	}
+}
+
+func TestComposeOutput_ParseOutput_empty(t *testing.T) {
+	var out ComposeOutput
+	out.ParseOutput("", "", "example_compose", loadFromString(prefixedServiceNameComposeYaml), false)
+	assert.NotNil(t, out.Resource)
+	assert.NotNil(t, out.Replicas)
+	assert.Empty(t, out.Events)
+}
@@ -0,0 +1,487 @@
+package compose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/compose/v2/pkg/progress"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/errdefs"
+)
+
+// PlanAction is an action planned for a container.
+type PlanAction string
+
+const (
+	PlanCreate   PlanAction = "create"
+	PlanRecreate PlanAction = "recreate"
+	PlanRemove   PlanAction = "remove"
+	PlanKeep     PlanAction = "keep"
+)
+
+// ErrPlanDrifted is returned by ComposeService.Apply if the state or the project changed after the plan was made.
+var ErrPlanDrifted = errors.New("plan drifted")
+
+// PlanOptions is options for ComposeService.Plan.
+type PlanOptions struct {
+	// Services limits the plan to them. All services of the wrapped project are planned if empty.
+	Services []string
+	// RemoveOrphans plans removal of containers of services which are not in the project.
+	// Otherwise they are planned to be kept.
+	RemoveOrphans bool
+}
+
+// PlannedContainer is an action planned for a replica.
+type PlannedContainer struct {
+	Service string
+	Num     int
+	// Container and ContainerID are empty if Action is PlanCreate.
+	Container   string
+	ContainerID string
+	Action      PlanAction
+	// Reason describes why Action is chosen.
+	Reason string
+	// ConfigHash is the hash labeled to the existing container.
+	ConfigHash string
+	// ExpectedHash is the hash computed from the project. It is empty for orphans.
+	ExpectedHash string
+}
+
+// Plan is containers changes which ComposeService.Apply would make.
+// It can be serialized into JSON and be applied later.
+type Plan struct {
+	ProjectName string
+	Options     PlanOptions
+	CreatedAt   time.Time
+	// Containers is sorted by service name, then by the replica number.
+	Containers []PlannedContainer
+}
+
+// Changes returns planned containers whose Action is not PlanKeep.
+func (p Plan) Changes() []PlannedContainer {
+	var changes []PlannedContainer
+	for _, c := range p.Containers {
+		if c.Action != PlanKeep {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// HasChanges reports whether applying p changes anything.
+func (p Plan) HasChanges() bool {
+	return len(p.Changes()) > 0
+}
+
+// Plan computes which containers would be created, recreated, removed or kept to converge to the wrapped project.
+//
+// Existing containers are compared with the project by the config hash labeled to them, as compose does.
+// A container is also recreated if the local image of its service is updated or not present.
+// Surplus replicas are removed, the highest replica number first,
+// and missing replicas are numbered after the highest existing one as compose does.
+// References to other services by network_mode, ipc, pid and volumes_from are resolved into container ids before hashing
+// as compose does, so a service is also recreated if the container it refers to is going to be replaced.
+//
+// Plan does not wait for other calls.
+func (s *ComposeService) Plan(ctx context.Context, options PlanOptions) (Plan, error) {
+	c, ctx := s.begin(ctx)
+	defer c.end()
+	return c.plan(ctx, options)
+}
+
+func (c *call) plan(ctx context.Context, options PlanOptions) (Plan, error) {
+	containers, err := c.service.Ps(ctx, c.projectName, api.PsOptions{All: true})
+	if err != nil {
+		return Plan{}, err
+	}
+	containers = slices.DeleteFunc(containers, func(c api.ContainerSummary) bool {
+		return c.Labels[api.OneoffLabel] == "True"
+	})
+
+	services := options.Services
+	if len(services) == 0 {
+		services = c.project.ServiceNames()
+	}
+
+	imageIDs := make(map[string]string)
+	apiClient := c.cli.Client()
+	for _, name := range services {
+		service, err := c.project.GetService(name)
+		if err != nil {
+			return Plan{}, err
+		}
+		image := api.GetImageNameOrDefault(service, c.project.Name)
+		if _, ok := imageIDs[image]; ok {
+			continue
+		}
+		inspected, _, err := apiClient.ImageInspectWithRaw(ctx, image)
+		if err != nil && !errdefs.IsNotFound(err) {
+			return Plan{}, err
+		}
+		imageIDs[image] = inspected.ID
+	}
+
+	planned, err := computePlan(c.project, services, containers, imageIDs, options.RemoveOrphans)
+	if err != nil {
+		return Plan{}, err
+	}
+	return Plan{
+		ProjectName: c.projectName,
+		Options:     options,
+		CreatedAt:   time.Now(),
+		Containers:  planned,
+	}, nil
+}
+
+// computePlan plans services of project against containers.
+// imageIDs maps image names to ids of local images. An empty id means that the image is not present.
+func computePlan(
+	project *types.Project,
+	services []string,
+	containers []api.ContainerSummary,
+	imageIDs map[string]string,
+	removeOrphans bool,
+) ([]PlannedContainer, error) {
+	var planned []PlannedContainer
+	// resolved maps services to the id of the container which compose resolves references to them into
+	// after converging, or to "" if the container is going to be created or recreated.
+	resolved := make(map[string]string)
+	visiting := make(map[string]bool)
+	var planService func(name string) error
+	planService = func(name string) error {
+		if _, ok := resolved[name]; ok || visiting[name] {
+			return nil
+		}
+		visiting[name] = true
+		defer delete(visiting, name)
+
+		service, err := project.GetService(name)
+		if err != nil {
+			return err
+		}
+		// compose converges referred services first and resolves references against their updated containers.
+		var changedRef string
+		err = resolveServiceReferences(&service, func(ref string) (string, error) {
+			if slices.Contains(services, ref) {
+				if err := planService(ref); err != nil {
+					return "", err
+				}
+			}
+			id, ok := resolved[ref]
+			if !ok {
+				// ref is not planned thus is kept as is.
+				if replicas := sortedByName(replicasOf(containers, ref)); len(replicas) > 0 {
+					id = replicas[0].ID
+				}
+			}
+			if id == "" && changedRef == "" {
+				changedRef = ref
+			}
+			return id, nil
+		})
+		if err != nil {
+			return err
+		}
+		hash, err := serviceHash(service)
+		if err != nil {
+			return err
+		}
+		imageID := imageIDs[api.GetImageNameOrDefault(service, project.Name)]
+
+		replicas := replicasOf(containers, name)
+		scale := 1
+		if service.Deploy != nil && service.Deploy.Replicas != nil {
+			scale = int(*service.Deploy.Replicas)
+		}
+		surplus := scaleDownTargets(replicas, scale)
+
+		var servicePlanned []PlannedContainer
+		for _, c := range replicas {
+			p := PlannedContainer{
+				Service:      name,
+				Num:          replicaNumber(c),
+				Container:    c.Name,
+				ContainerID:  c.ID,
+				Action:       PlanKeep,
+				ConfigHash:   c.Labels[api.ConfigHashLabel],
+				ExpectedHash: hash,
+			}
+			switch {
+			case slices.ContainsFunc(surplus, func(s api.ContainerSummary) bool { return s.ID == c.ID }):
+				p.Action, p.Reason = PlanRemove, fmt.Sprintf("exceeds replicas %d", scale)
+			case changedRef != "":
+				p.Action, p.Reason = PlanRecreate, fmt.Sprintf("container of service %s is created or recreated", changedRef)
+			case p.ConfigHash != hash:
+				p.Action, p.Reason = PlanRecreate, "config changed"
+			case imageID == "":
+				p.Action, p.Reason = PlanRecreate, "image is not present locally"
+			case c.Labels[api.ImageDigestLabel] != imageID:
+				p.Action, p.Reason = PlanRecreate, "image updated"
+			}
+			servicePlanned = append(servicePlanned, p)
+		}
+		// compose numbers new replicas after the highest existing one, rather than filling gaps.
+		next := 1
+		for _, c := range replicas {
+			next = max(next, replicaNumber(c)+1)
+		}
+		for i := 0; i < scale-len(replicas); i++ {
+			num := next + i
+			servicePlanned = append(servicePlanned, PlannedContainer{
+				Service:      name,
+				Num:          num,
+				Action:       PlanCreate,
+				Reason:       "missing replica",
+				ExpectedHash: hash,
+			})
+		}
+		planned = append(planned, servicePlanned...)
+
+		// compose resolves references into the container sorted first by name among the updated ones.
+		var first *PlannedContainer
+		var firstName string
+		for i, p := range servicePlanned {
+			if p.Action == PlanRemove {
+				continue
+			}
+			if p.Container == "" {
+				p.Container = containerName(project.Name, service, p.Num)
+			}
+			if first == nil || p.Container < firstName {
+				first, firstName = &servicePlanned[i], p.Container
+			}
+		}
+		resolved[name] = ""
+		if first != nil && first.Action == PlanKeep {
+			resolved[name] = first.ContainerID
+		}
+		return nil
+	}
+	for _, name := range services {
+		if err := planService(name); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range containers {
+		// containers of disabled services, e.g. by profiles, are not orphans.
+		if _, err := project.GetService(c.Service); err == nil {
+			continue
+		}
+		if _, err := project.GetDisabledService(c.Service); err == nil {
+			continue
+		}
+		p := PlannedContainer{
+			Service:     c.Service,
+			Num:         replicaNumber(c),
+			Container:   c.Name,
+			ContainerID: c.ID,
+			Action:      PlanKeep,
+			Reason:      "orphan",
+			ConfigHash:  c.Labels[api.ConfigHashLabel],
+		}
+		if removeOrphans {
+			p.Action = PlanRemove
+		}
+		planned = append(planned, p)
+	}
+
+	slices.SortStableFunc(planned, func(i, j PlannedContainer) int {
+		switch {
+		case i.Service < j.Service:
+			return -1
+		case i.Service > j.Service:
+			return 1
+		}
+		return i.Num - j.Num
+	})
+	return planned, nil
+}
+
+// Apply converges containers as plan describes.
+//
+// Apply first plans again with the same options and returns an error wrapping ErrPlanDrifted
+// if the result differs from plan, i.e. containers or the wrapped project changed after plan was made.
+// Then it removes containers planned to be removed, creates and recreates containers through compose,
+// and starts only created and recreated containers. Containers planned to be kept are left as they are,
+// and so are containers of services not in plan, e.g. stopped ones are not started.
+//
+// Apply waits for other calls mutating services in plan, so the check and changes are made at once.
+//
+// In dry run mode, Apply reports what it would do in the returned ComposeOutput without changing anything.
+func (s *ComposeService) Apply(ctx context.Context, plan Plan) (ComposeOutput, error) {
+	c, ctx := s.begin(ctx)
+	defer c.end()
+
+	if plan.ProjectName != s.projectName {
+		return ComposeOutput{}, fmt.Errorf("%w: plan is for project %q but service is for %q", ErrPlanDrifted, plan.ProjectName, s.projectName)
+	}
+	var planned, orphans []string
+	for _, p := range plan.Containers {
+		if _, err := c.project.GetService(p.Service); err == nil {
+			planned = append(planned, p.Service)
+		} else {
+			orphans = append(orphans, p.Service)
+		}
+	}
+	if err := c.lock(ctx, planned, true, append(orphans, projectResources)...); err != nil {
+		return ComposeOutput{}, err
+	}
+
+	current, err := c.plan(ctx, plan.Options)
+	if err != nil {
+		return ComposeOutput{}, err
+	}
+	if drifted := diffPlannedContainers(plan.Containers, current.Containers); len(drifted) > 0 {
+		return ComposeOutput{}, fmt.Errorf("%w: %v", ErrPlanDrifted, drifted)
+	}
+
+	var (
+		toRemove []api.ContainerSummary
+		toStart  []string
+		services []string
+	)
+	for _, p := range plan.Containers {
+		switch p.Action {
+		case PlanRemove:
+			toRemove = append(toRemove, api.ContainerSummary{ID: p.ContainerID, Name: p.Container})
+		case PlanCreate, PlanRecreate:
+			if p.Container == "" {
+				service, err := c.project.GetService(p.Service)
+				if err != nil {
+					return ComposeOutput{}, err
+				}
+				p.Container = containerName(s.projectName, service, p.Num)
+			}
+			toStart = append(toStart, p.Container)
+			if !slices.Contains(services, p.Service) {
+				services = append(services, p.Service)
+			}
+		}
+	}
+
+	if len(toRemove) > 0 {
+		err := c.runWithProgress(ctx, func(ctx context.Context) error {
+			return c.removeReplicas(ctx, toRemove)
+		})
+		if err != nil {
+			return c.parseOutput(), err
+		}
+	}
+
+	if len(services) > 0 {
+		// compose would converge every service of the project given, including ones not in the plan.
+		project, err := forServices(c.project, services, false)
+		if err != nil {
+			return ComposeOutput{}, err
+		}
+		err = c.service.Create(ctx, project, api.CreateOptions{
+			Services:             services,
+			Recreate:             api.RecreateDiverged,
+			RecreateDependencies: api.RecreateNever,
+			Inherit:              true,
+			IgnoreOrphans:        true,
+		})
+		if err != nil {
+			return c.parseOutput(), err
+		}
+		err = c.runWithProgress(ctx, func(ctx context.Context) error {
+			return c.startContainers(ctx, toStart)
+		})
+		if err != nil {
+			return c.parseOutput(), err
+		}
+	}
+	return c.parseOutput(), nil
+}
+
+// startContainers starts containers by name in order, reporting progress like compose does.
+func (c *call) startContainers(ctx context.Context, names []string) error {
+	w := progress.ContextWriter(ctx)
+	apiClient := c.cli.Client()
+	for _, name := range names {
+		eventName := "Container " + name
+		w.Event(progress.StartingEvent(eventName))
+		if err := apiClient.ContainerStart(ctx, name, dockertypes.ContainerStartOptions{}); err != nil {
+			w.Event(progress.ErrorMessageEvent(eventName, "Error while Starting"))
+			return err
+		}
+		w.Event(progress.StartedEvent(eventName))
+	}
+	return nil
+}
+
+// Mimicking getContainerName of compose.
+func containerName(projectName string, service types.ServiceConfig, num int) string {
+	if service.ContainerName != "" {
+		return service.ContainerName
+	}
+	return fmt.Sprintf("%[1]s%[4]s%[2]s%[4]s%[3]d", projectName, service.Name, num, api.Separator)
+}
+
+// diffPlannedContainers returns descriptions of planned containers which differ between planned and current.
+func diffPlannedContainers(planned, current []PlannedContainer) []string {
+	var drifted []string
+	for _, p := range planned {
+		if !slices.Contains(current, p) {
+			drifted = append(drifted, fmt.Sprintf("%s #%d planned to %s", p.Service, p.Num, p.Action))
+		}
+	}
+	for _, c := range current {
+		if !slices.Contains(planned, c) {
+			drifted = append(drifted, fmt.Sprintf("%s #%d now needs to %s", c.Service, c.Num, c.Action))
+		}
+	}
+	return drifted
+}
+
+func replicasOf(containers []api.ContainerSummary, service string) []api.ContainerSummary {
+	var replicas []api.ContainerSummary
+	for _, c := range containers {
+		if c.Service == service {
+			replicas = append(replicas, c)
+		}
+	}
+	return replicas
+}
+
+func sortedByName(containers []api.ContainerSummary) []api.ContainerSummary {
+	slices.SortFunc(containers, func(i, j api.ContainerSummary) int { return strings.Compare(i.Name, j.Name) })
+	return containers
+}
+
+// resolveServiceReferences rewrites references of service to other services into ids of containers, mimicking
+// resolveServiceReferences of compose, which applies it before hashing.
+// resolve returns the id of the container which a reference to the service is resolved into.
+func resolveServiceReferences(service *types.ServiceConfig, resolve func(service string) (string, error)) error {
+	service.VolumesFrom = slices.Clone(service.VolumesFrom)
+	for i, vol := range service.VolumesFrom {
+		spec := strings.Split(vol, ":")
+		if spec[0] == "container" {
+			service.VolumesFrom[i] = spec[1]
+			continue
+		}
+		id, err := resolve(spec[0])
+		if err != nil {
+			return err
+		}
+		service.VolumesFrom[i] = id
+	}
+	for _, mode := range []*string{&service.NetworkMode, &service.Ipc, &service.Pid} {
+		name, ok := strings.CutPrefix(*mode, types.ServicePrefix)
+		if !ok {
+			continue
+		}
+		id, err := resolve(name)
+		if err != nil {
+			return err
+		}
+		*mode = types.ContainerPrefix + id
+	}
+	return nil
+}
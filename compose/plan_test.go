@@ -0,0 +1,299 @@
+package compose
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/compose/v2/pkg/api"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputePlan(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	project := loadFromString(prefixedServiceNameComposeYaml)
+	setReplicas(project, map[string]int{"web": 2, "web-admin": 2})
+
+	hashOf := func(name string) string {
+		service, err := project.GetService(name)
+		require.NoError(err)
+		hash, err := serviceHash(service)
+		require.NoError(err)
+		return hash
+	}
+	container := func(service string, num int, hash, imageID string) api.ContainerSummary {
+		name := project.Name + "-" + service + "-" + strconv.Itoa(num)
+		return api.ContainerSummary{
+			ID:      name + "-id",
+			Name:    name,
+			Service: service,
+			Labels: map[string]string{
+				api.ContainerNumberLabel: strconv.Itoa(num),
+				api.ConfigHashLabel:      hash,
+				api.ImageDigestLabel:     imageID,
+			},
+		}
+	}
+
+	imageIDs := map[string]string{"ubuntu:jammy-20230624": "sha256:new"}
+	containers := []api.ContainerSummary{
+		container("web", 3, hashOf("web"), "sha256:new"),
+		container("web", 1, hashOf("web"), "sha256:old"),
+		container("web", 2, hashOf("web"), "sha256:new"),
+		container("web-admin", 1, "stale", "sha256:new"),
+		container("named", 1, hashOf("named"), "sha256:new"),
+		container("removed", 1, "whatever", "sha256:new"),
+	}
+
+	planned, err := computePlan(project, project.ServiceNames(), containers, imageIDs, false)
+	require.NoError(err)
+
+	type actionOf struct {
+		Service string
+		Num     int
+		Action  PlanAction
+	}
+	var actions []actionOf
+	for _, p := range planned {
+		actions = append(actions, actionOf{p.Service, p.Num, p.Action})
+	}
+	assert.Equal([]actionOf{
+		{"named", 1, PlanKeep},
+		{"removed", 1, PlanKeep},
+		{"web", 1, PlanRecreate},
+		{"web", 2, PlanKeep},
+		{"web", 3, PlanRemove},
+		{"web-admin", 1, PlanRecreate},
+		{"web-admin", 2, PlanCreate},
+	}, actions)
+	assert.Equal("orphan", planned[1].Reason)
+	assert.Equal("image updated", planned[2].Reason)
+	assert.Equal("config changed", planned[5].Reason)
+
+	planned, err = computePlan(project, []string{"web-admin"}, containers, map[string]string{}, true)
+	require.NoError(err)
+	actions = actions[:0]
+	for _, p := range planned {
+		actions = append(actions, actionOf{p.Service, p.Num, p.Action})
+	}
+	assert.Equal([]actionOf{
+		{"removed", 1, PlanRemove},
+		{"web-admin", 1, PlanRecreate},
+		{"web-admin", 2, PlanCreate},
+	}, actions)
+
+	// a gap in replica numbers is not filled.
+	setReplicas(project, map[string]int{"web": 3})
+	planned, err = computePlan(project, []string{"web"}, containers[:2], imageIDs, false)
+	require.NoError(err)
+	actions = actions[:0]
+	for _, p := range planned {
+		actions = append(actions, actionOf{p.Service, p.Num, p.Action})
+	}
+	assert.Equal([]actionOf{
+		{"web", 1, PlanRecreate},
+		{"web", 3, PlanKeep},
+		{"web", 4, PlanCreate},
+	}, actions)
+
+	_, err = computePlan(project, []string{"nonexistent"}, containers, imageIDs, false)
+	assert.Error(err)
+}
+
+func TestComputePlan_serviceReferences(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	project := loadFromString(`
+name: refs
+services:
+  db:
+    image: ubuntu:jammy-20230624
+  app:
+    image: ubuntu:jammy-20230624
+    network_mode: service:db
+    volumes_from:
+      - db:ro
+`)
+	const imageID = "sha256:img"
+	imageIDs := map[string]string{"ubuntu:jammy-20230624": imageID}
+
+	hashOf := func(name, dbID string) string {
+		service, err := project.GetService(name)
+		require.NoError(err)
+		if name == "app" {
+			service.NetworkMode = "container:" + dbID
+			service.VolumesFrom = []string{dbID}
+		}
+		hash, err := serviceHash(service)
+		require.NoError(err)
+		return hash
+	}
+	container := func(service, id, hash string) api.ContainerSummary {
+		return api.ContainerSummary{
+			ID:      id,
+			Name:    "refs-" + service + "-1",
+			Service: service,
+			Labels: map[string]string{
+				api.ContainerNumberLabel: "1",
+				api.ConfigHashLabel:      hash,
+				api.ImageDigestLabel:     imageID,
+			},
+		}
+	}
+	actionsOf := func(planned []PlannedContainer) map[string]PlanAction {
+		actions := make(map[string]PlanAction)
+		for _, p := range planned {
+			actions[p.Service] = p.Action
+		}
+		return actions
+	}
+
+	// the hash labeled by compose is of app referring to the container of db.
+	containers := []api.ContainerSummary{
+		container("db", "db-id", hashOf("db", "")),
+		container("app", "app-id", hashOf("app", "db-id")),
+	}
+	planned, err := computePlan(project, project.ServiceNames(), containers, imageIDs, false)
+	require.NoError(err)
+	assert.Equal(map[string]PlanAction{"db": PlanKeep, "app": PlanKeep}, actionsOf(planned))
+
+	// app refers to the container of db which is going to be replaced.
+	containers[0].Labels[api.ConfigHashLabel] = "stale"
+	planned, err = computePlan(project, project.ServiceNames(), containers, imageIDs, false)
+	require.NoError(err)
+	assert.Equal(map[string]PlanAction{"db": PlanRecreate, "app": PlanRecreate}, actionsOf(planned))
+	assert.Equal("app", planned[0].Service)
+	assert.Equal("container of service db is created or recreated", planned[0].Reason)
+
+	// db is not planned thus its container is kept.
+	planned, err = computePlan(project, []string{"app"}, containers, imageIDs, false)
+	require.NoError(err)
+	assert.Equal(map[string]PlanAction{"app": PlanKeep}, actionsOf(planned))
+
+	// app was created while another container of db existed.
+	containers[0].Labels[api.ConfigHashLabel] = hashOf("db", "")
+	containers[1].Labels[api.ConfigHashLabel] = hashOf("app", "old-db-id")
+	planned, err = computePlan(project, project.ServiceNames(), containers, imageIDs, false)
+	require.NoError(err)
+	assert.Equal(map[string]PlanAction{"db": PlanKeep, "app": PlanRecreate}, actionsOf(planned))
+	assert.Equal("config changed", planned[0].Reason)
+}
+
+func TestPlan_json(t *testing.T) {
+	assert := assert.New(t)
+
+	plan := Plan{
+		ProjectName: "example_compose",
+		Options:     PlanOptions{Services: []string{"web"}, RemoveOrphans: true},
+		CreatedAt:   time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC),
+		Containers: []PlannedContainer{
+			{Service: "web", Num: 1, Container: "example_compose-web-1", ContainerID: "abc", Action: PlanKeep, ConfigHash: "h", ExpectedHash: "h"},
+			{Service: "web", Num: 2, Action: PlanCreate, Reason: "missing replica", ExpectedHash: "h"},
+		},
+	}
+	bin, err := json.Marshal(plan)
+	assert.NoError(err)
+	var decoded Plan
+	assert.NoError(json.Unmarshal(bin, &decoded))
+	assert.Equal(plan, decoded)
+
+	assert.True(decoded.HasChanges())
+	assert.Len(decoded.Changes(), 1)
+	assert.Empty(diffPlannedContainers(plan.Containers, decoded.Containers))
+	decoded.Containers[1].Action = PlanKeep
+	assert.Equal(
+		[]string{"web #2 planned to create", "web #2 now needs to keep"},
+		diffPlannedContainers(plan.Containers, decoded.Containers),
+	)
+}
+
+// fakeDockerCli is a docker cli whose client is a fakeDockerClient.
+type fakeDockerCli struct {
+	command.Cli
+	client *fakeDockerClient
+}
+
+func (c *fakeDockerCli) Client() client.APIClient { return c.client }
+
+type fakeDockerClient struct {
+	client.APIClient
+	imageID string
+
+	mu      sync.Mutex
+	started []string
+}
+
+func (c *fakeDockerClient) ImageInspectWithRaw(ctx context.Context, image string) (dockertypes.ImageInspect, []byte, error) {
+	return dockertypes.ImageInspect{ID: c.imageID}, nil, nil
+}
+
+func (c *fakeDockerClient) ContainerStart(ctx context.Context, container string, options dockertypes.ContainerStartOptions) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.started = append(c.started, container)
+	return nil
+}
+
+func TestComposeService_Apply_unplanned_services(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	const imageID = "sha256:img"
+	dockerClient := &fakeDockerClient{imageID: imageID}
+	composeService := NewComposeService(
+		"example_compose",
+		loadFromString(waitComposeYaml),
+		&fakeDockerCli{client: dockerClient},
+	)
+
+	stopped := func(service, hash string) api.ContainerSummary {
+		name := "example_compose-" + service + "-1"
+		return api.ContainerSummary{
+			ID:      name + "-id",
+			Name:    name,
+			Service: service,
+			State:   "exited",
+			Labels: map[string]string{
+				api.ContainerNumberLabel: "1",
+				api.ConfigHashLabel:      hash,
+				api.ImageDigestLabel:     imageID,
+			},
+		}
+	}
+	// db is not planned and its container is stopped.
+	containers := []api.ContainerSummary{stopped("worker", "stale"), stopped("db", "stale")}
+	var created []string
+	composeService.newService = func(command.Cli) api.Service {
+		return &api.ServiceProxy{
+			PsFn: func(ctx context.Context, projectName string, options api.PsOptions) ([]api.ContainerSummary, error) {
+				return containers, nil
+			},
+			CreateFn: func(ctx context.Context, project *types.Project, options api.CreateOptions) error {
+				created = project.ServiceNames()
+				return nil
+			},
+		}
+	}
+
+	plan, err := composeService.Plan(context.Background(), PlanOptions{Services: []string{"worker"}})
+	require.NoError(err)
+	require.Len(plan.Changes(), 2)
+
+	out, err := composeService.Apply(context.Background(), plan)
+	require.NoError(err)
+	assert.Equal([]string{"worker"}, created)
+	assert.Equal([]string{"example_compose-worker-1", "example_compose-worker-2"}, dockerClient.started)
+	// events of starting are recorded from typed progress events.
+	assert.Equal(Started, out.Replicas[ResourceKey{ResourceType: Container, Name: "worker", Num: 2}].StateType)
+}
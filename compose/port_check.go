@@ -0,0 +1,209 @@
+package compose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/docker/compose/v2/pkg/api"
+	dockertypes "github.com/docker/docker/api/types"
+)
+
+// ErrPortConflict is wrapped by the error returned from PortConflictReport.Err.
+var ErrPortConflict = errors.New("port conflict")
+
+// PortConflictSource is what holds a conflicting host port.
+type PortConflictSource string
+
+const (
+	// PortConflictContainer is a running container of another project, or of a service not being checked.
+	PortConflictContainer PortConflictSource = "container"
+	// PortConflictHost is a listening socket on the host which no container publishes.
+	PortConflictHost PortConflictSource = "host"
+)
+
+// PortConflict is a host port published by a service which is already in use.
+type PortConflict struct {
+	Service  string
+	HostIP   string
+	Port     int
+	Protocol string
+	Source   PortConflictSource
+	// Project, ContainerService, Container and ContainerID describe the container holding the port.
+	// They are set only if Source is PortConflictContainer.
+	Project          string
+	ContainerService string
+	Container        string
+	ContainerID      string
+	// LocalAddress is the address the host socket is bound to. It is set only if Source is PortConflictHost.
+	LocalAddress string
+}
+
+func (c PortConflict) String() string {
+	port := publishedPort{HostIP: c.HostIP, Port: c.Port, Protocol: c.Protocol}
+	switch c.Source {
+	case PortConflictContainer:
+		return fmt.Sprintf("service %q: host port %s is used by container %s", c.Service, port, c.Container)
+	default:
+		return fmt.Sprintf("service %q: host port %s is used by a socket listening on %s", c.Service, port, c.LocalAddress)
+	}
+}
+
+// PortCheckOptions is options for ComposeService.CheckPorts.
+type PortCheckOptions struct {
+	// Services limits the check to them. All services of the wrapped project are checked if empty.
+	Services []string
+	// SkipHost skips checking listening sockets of the host.
+	SkipHost bool
+}
+
+// PortConflictReport is the result of ComposeService.CheckPorts.
+type PortConflictReport struct {
+	ProjectName string
+	Conflicts   []PortConflict
+	// HostChecked reports whether listening sockets of the host were checked.
+	// They are not if PortCheckOptions.SkipHost is set, the daemon is not reached via a local socket,
+	// or reading sockets is not supported on the platform.
+	HostChecked bool
+}
+
+// HasConflicts reports whether any conflict is found.
+func (r PortConflictReport) HasConflicts() bool {
+	return len(r.Conflicts) > 0
+}
+
+// Err returns an error wrapping ErrPortConflict which describes conflicts, or nil if there is none.
+func (r PortConflictReport) Err() error {
+	if !r.HasConflicts() {
+		return nil
+	}
+	descriptions := make([]string, len(r.Conflicts))
+	for i, c := range r.Conflicts {
+		descriptions[i] = c.String()
+	}
+	return fmt.Errorf("%w: project %q: %s", ErrPortConflict, r.ProjectName, strings.Join(descriptions, ", "))
+}
+
+// listeningSocket is a socket listening on the host.
+type listeningSocket struct {
+	IP       string
+	Port     int
+	Protocol string
+}
+
+// CheckPorts checks whether host ports published by services are already in use,
+// so that conflicts are found before Create or Up fails with a docker error.
+//
+// Published ports are compared against running containers of all projects on the daemon and,
+// if the daemon is reached via a local socket, listening sockets of the host.
+// Containers of the checked services of this project are excluded, since Create recreates them.
+// Conflicts between services of the project itself are not reported. Use Linter for them.
+//
+// Ports which docker assigns randomly, i.e. without published port, are not checked.
+func (s *ComposeService) CheckPorts(ctx context.Context, options PortCheckOptions) (PortConflictReport, error) {
+	c, ctx := s.begin(ctx)
+	defer c.end()
+
+	services := options.Services
+	if len(services) == 0 {
+		services = c.project.ServiceNames()
+	}
+
+	containers, err := c.cli.Client().ContainerList(ctx, dockertypes.ContainerListOptions{})
+	if err != nil {
+		return PortConflictReport{}, err
+	}
+
+	report := PortConflictReport{ProjectName: c.projectName}
+	var sockets []listeningSocket
+	if !options.SkipHost && isLocalDaemon(c.cli.DockerEndpoint().Host) {
+		sockets, err = listeningSockets()
+		switch {
+		case errors.Is(err, errors.ErrUnsupported):
+		case err != nil:
+			return PortConflictReport{}, err
+		default:
+			report.HostChecked = true
+		}
+	}
+
+	report.Conflicts, err = findPortConflicts(c.project, c.projectName, services, containers, sockets)
+	if err != nil {
+		return PortConflictReport{}, err
+	}
+	return report, nil
+}
+
+func isLocalDaemon(host string) bool {
+	return host == "" || strings.HasPrefix(host, "unix://") || strings.HasPrefix(host, "npipe://")
+}
+
+// findPortConflicts compares ports published by services against ones published by containers and sockets.
+// Sockets bound to ports which a running container publishes are ignored, since they are held by docker-proxy for it.
+func findPortConflicts(
+	project *types.Project,
+	projectName string,
+	services []string,
+	containers []dockertypes.Container,
+	sockets []listeningSocket,
+) ([]PortConflict, error) {
+	var containerPorts []publishedPort
+	for _, container := range containers {
+		for _, port := range container.Ports {
+			if port.PublicPort != 0 {
+				containerPorts = append(containerPorts, publishedPort{HostIP: port.IP, Port: int(port.PublicPort), Protocol: port.Type})
+			}
+		}
+	}
+
+	var conflicts []PortConflict
+	for _, name := range services {
+		service, err := project.GetService(name)
+		if err != nil {
+			return nil, err
+		}
+		ports, err := publishedPorts(service)
+		if err != nil {
+			return nil, err
+		}
+
+	PORTS:
+		for _, port := range ports {
+			conflict := PortConflict{Service: name, HostIP: port.HostIP, Port: port.Port, Protocol: port.Protocol}
+			for _, container := range containers {
+				if container.Labels[api.ProjectLabel] == projectName && slices.Contains(services, container.Labels[api.ServiceLabel]) {
+					continue
+				}
+				for _, p := range container.Ports {
+					if p.PublicPort == 0 || !port.overlaps(publishedPort{HostIP: p.IP, Port: int(p.PublicPort), Protocol: p.Type}) {
+						continue
+					}
+					conflict.Source = PortConflictContainer
+					conflict.Project = container.Labels[api.ProjectLabel]
+					conflict.ContainerService = container.Labels[api.ServiceLabel]
+					if len(container.Names) > 0 {
+						conflict.Container = strings.TrimPrefix(container.Names[0], "/")
+					}
+					conflict.ContainerID = container.ID
+					conflicts = append(conflicts, conflict)
+					continue PORTS
+				}
+			}
+			for _, socket := range sockets {
+				held := publishedPort{HostIP: socket.IP, Port: socket.Port, Protocol: socket.Protocol}
+				if !port.overlaps(held) || slices.ContainsFunc(containerPorts, held.overlaps) {
+					continue
+				}
+				conflict.Source = PortConflictHost
+				conflict.LocalAddress = net.JoinHostPort(socket.IP, fmt.Sprint(socket.Port))
+				conflicts = append(conflicts, conflict)
+				continue PORTS
+			}
+		}
+	}
+	return conflicts, nil
+}
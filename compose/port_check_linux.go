@@ -0,0 +1,87 @@
+package compose
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	tcpListen = "0A"
+	// udpUnconnected is TCP_CLOSE, which bound but unconnected udp sockets are in.
+	udpUnconnected = "07"
+)
+
+// listeningSockets reads tcp sockets in LISTEN state and unconnected udp sockets from /proc/net.
+func listeningSockets() ([]listeningSocket, error) {
+	var sockets []listeningSocket
+	for _, table := range []struct{ file, protocol, state string }{
+		{"/proc/net/tcp", "tcp", tcpListen},
+		{"/proc/net/tcp6", "tcp", tcpListen},
+		{"/proc/net/udp", "udp", udpUnconnected},
+		{"/proc/net/udp6", "udp", udpUnconnected},
+	} {
+		f, err := os.Open(table.file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// ipv6 may be disabled.
+				continue
+			}
+			return nil, err
+		}
+		parsed, err := parseProcNet(f, table.protocol, table.state)
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", table.file, err)
+		}
+		sockets = append(sockets, parsed...)
+	}
+	return sockets, nil
+}
+
+// parseProcNet parses a table of /proc/net/{tcp,tcp6,udp,udp6} and returns sockets in state.
+func parseProcNet(r io.Reader, protocol, state string) ([]listeningSocket, error) {
+	var sockets []listeningSocket
+	scanner := bufio.NewScanner(r)
+	// skip the header.
+	scanner.Scan()
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[3] != state {
+			continue
+		}
+		addr, portHex, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			return nil, fmt.Errorf("malformed local address %q", fields[1])
+		}
+		ip, err := parseProcNetIP(addr)
+		if err != nil {
+			return nil, err
+		}
+		port, err := strconv.ParseUint(portHex, 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("malformed local address %q: %w", fields[1], err)
+		}
+		sockets = append(sockets, listeningSocket{IP: ip.String(), Port: int(port), Protocol: protocol})
+	}
+	return sockets, scanner.Err()
+}
+
+// parseProcNetIP parses an address which is printed as 32 bit words in host byte order.
+func parseProcNetIP(s string) (net.IP, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || (len(b) != net.IPv4len && len(b) != net.IPv6len) {
+		return nil, fmt.Errorf("malformed ip address %q", s)
+	}
+	ip := make(net.IP, len(b))
+	for i := 0; i < len(b); i += 4 {
+		binary.NativeEndian.PutUint32(ip[i:], binary.BigEndian.Uint32(b[i:]))
+	}
+	return ip, nil
+}
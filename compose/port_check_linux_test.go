@@ -0,0 +1,40 @@
+package compose
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProcNet(t *testing.T) {
+	tcp := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:07E8 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 662 1 000000005d0bf9d3 100 0 0 10 0
+   1: 0100007F:BC8F 00000000:0000 0A 00000000:00000000 00:00000000 00000000 65534        0 926 1 000000009f1ab9e0 100 0 0 10 0
+   2: 0100007F:E016 0100007F:BC8F 01 00000000:00000000 02:00000A0B 00000000     0        0 66060 2 000000008ba884c6 20 4 0 19 -1
+`
+	sockets, err := parseProcNet(strings.NewReader(tcp), "tcp", tcpListen)
+	require.NoError(t, err)
+	assert.Equal(t, []listeningSocket{
+		{IP: "0.0.0.0", Port: 2024, Protocol: "tcp"},
+		{IP: "127.0.0.1", Port: 48271, Protocol: "tcp"},
+	}, sockets)
+
+	tcp6 := `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:1F90 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1 1 0 100 0 0 10 0
+   1: 00000000000000000000000001000000:0035 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 2 1 0 100 0 0 10 0
+`
+	sockets, err = parseProcNet(strings.NewReader(tcp6), "tcp", tcpListen)
+	require.NoError(t, err)
+	assert.Equal(t, []listeningSocket{
+		{IP: "::", Port: 8080, Protocol: "tcp"},
+		{IP: "::1", Port: 53, Protocol: "tcp"},
+	}, sockets)
+
+	_, err = parseProcNet(strings.NewReader("header\n 0: zz:0050 00000000:0000 0A\n"), "tcp", tcpListen)
+	assert.Error(t, err)
+
+	_, err = listeningSockets()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package compose
+
+import "errors"
+
+func listeningSockets() ([]listeningSocket, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,92 @@
+package compose
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/docker/compose/v2/pkg/api"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindPortConflicts(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	project, err := NewProjectBuilder("web").
+		Service(types.ServiceConfig{Name: "app", Image: "ubuntu:jammy-20230624", Ports: []types.ServicePortConfig{
+			{Target: 80, Published: "8080", Protocol: "tcp"},
+			{Target: 443, Published: "8443", Protocol: "tcp"},
+			{Target: 53, Published: "5353", Protocol: "udp"},
+			{Target: 9000},
+		}}).
+		Service(types.ServiceConfig{Name: "admin", Image: "ubuntu:jammy-20230624", Ports: []types.ServicePortConfig{
+			{HostIP: "127.0.0.1", Target: 80, Published: "9090-9091", Protocol: "tcp"},
+		}}).
+		Build(context.Background())
+	require.NoError(err)
+
+	containers := []dockertypes.Container{
+		{
+			// recreated by Create, thus not a conflict.
+			ID:     "own",
+			Names:  []string{"/web-app-1"},
+			Labels: map[string]string{api.ProjectLabel: "web", api.ServiceLabel: "app"},
+			Ports:  []dockertypes.Port{{IP: "0.0.0.0", PrivatePort: 80, PublicPort: 8080, Type: "tcp"}},
+		},
+		{
+			ID:     "other",
+			Names:  []string{"/other-proxy-1"},
+			Labels: map[string]string{api.ProjectLabel: "other", api.ServiceLabel: "proxy"},
+			Ports: []dockertypes.Port{
+				{IP: "0.0.0.0", PrivatePort: 443, PublicPort: 8443, Type: "tcp"},
+				{PrivatePort: 80, Type: "tcp"},
+				{IP: "0.0.0.0", PrivatePort: 53, PublicPort: 5353, Type: "tcp"},
+			},
+		},
+	}
+	sockets := []listeningSocket{
+		// docker-proxy for the containers.
+		{IP: "0.0.0.0", Port: 8080, Protocol: "tcp"},
+		{IP: "0.0.0.0", Port: 8443, Protocol: "tcp"},
+		{IP: "::", Port: 8443, Protocol: "tcp"},
+		// not overlapping with 127.0.0.1.
+		{IP: "192.168.1.2", Port: 9090, Protocol: "tcp"},
+		{IP: "127.0.0.1", Port: 9091, Protocol: "tcp"},
+	}
+
+	conflicts, err := findPortConflicts(project, "web", project.ServiceNames(), containers, sockets)
+	require.NoError(err)
+	assert.ElementsMatch([]PortConflict{
+		{
+			Service: "app", Port: 8443, Protocol: "tcp", Source: PortConflictContainer,
+			Project: "other", ContainerService: "proxy", Container: "other-proxy-1", ContainerID: "other",
+		},
+		{
+			Service: "admin", HostIP: "127.0.0.1", Port: 9091, Protocol: "tcp", Source: PortConflictHost,
+			LocalAddress: "127.0.0.1:9091",
+		},
+	}, conflicts)
+
+	// containers of services not being checked conflict.
+	conflicts, err = findPortConflicts(project, "web", []string{"admin"}, containers[:1], []listeningSocket{{IP: "0.0.0.0", Port: 9090, Protocol: "tcp"}})
+	require.NoError(err)
+	require.Len(conflicts, 1)
+	assert.Equal(PortConflictHost, conflicts[0].Source)
+	conflicts, err = findPortConflicts(project, "other", []string{"app"}, containers[:1], nil)
+	require.NoError(err)
+	require.Len(conflicts, 1)
+	assert.Equal("web-app-1", conflicts[0].Container)
+
+	_, err = findPortConflicts(project, "web", []string{"nonexistent"}, nil, nil)
+	assert.Error(err)
+
+	report := PortConflictReport{ProjectName: "web"}
+	assert.NoError(report.Err())
+	report.Conflicts = conflicts
+	assert.True(errors.Is(report.Err(), ErrPortConflict))
+	assert.EqualError(report.Err(), `port conflict: project "web": service "app": host port 0.0.0.0:8080/tcp is used by container web-app-1`)
+}
@@ -0,0 +1,282 @@
+package compose
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/compose/v2/pkg/progress"
+)
+
+// ProgressEvent is a progress event emitted by compose.
+//
+// Events emitted by ComposeService itself, e.g. while Scale removes replicas, are taken from typed progress.Event values
+// through a progress.Writer in the context.
+// compose, however, does not allow callers to install their own progress.Writer:
+// progress.RunWithStatus, which every method of compose runs in, creates one on the output stream of the docker cli
+// and replaces any writer in the context by it.
+// Since its plain writer prints each progress.Event as a single line of form `ID Text StatusText`,
+// ComposeService captures those lines as they are written and decodes them immediately.
+// Thus events of compose depend on its wording. A line which can not be decoded,
+// e.g. after compose has changed it, yields no event and is only kept in ComposeOutput.Out or Err.
+type ProgressEvent struct {
+	ComposeOutputLine
+	// ID is the resource id of the event, e.g. "Container testdata-sample_service-1".
+	ID string
+	// Text is the rest of the line after ID.
+	// It is kept as is even if StateType is unknown to this package.
+	Text string
+	// Time is the time when the event is received.
+	Time time.Time
+}
+
+// DecodeProgressEvent decodes a line printed by compose's plain progress writer.
+// Unlike DecodeComposeOutputLine, it does not fail on unknown state as long as the resource is known.
+// In that case StateType of returned event is empty and Text holds the state as is.
+// It returns an error if project is nil, since resources are resolved against it.
+func DecodeProgressEvent(line string, projectName string, project *types.Project, isDryRunMode bool) (ProgressEvent, error) {
+	decoded, id, text, err := decodeLine(line, projectName, project, isDryRunMode)
+	if err != nil {
+		return ProgressEvent{}, err
+	}
+	return ProgressEvent{
+		ComposeOutputLine: decoded,
+		ID:                id,
+		Text:              text,
+	}, nil
+}
+
+// decodeEvent decodes a typed progress.Event.
+// The resource is resolved from event.ID, and the state from Text and StatusText as lines are,
+// so that the result is same as one of the line printed for event.
+// Status, ParentID and progress of layers are taken from fields as they are, not from the text.
+func decodeEvent(event progress.Event, projectName string, project *types.Project, isDryRunMode bool) (ProgressEvent, error) {
+	decoded, id, _, err := decodeLine(event.ID, projectName, project, isDryRunMode)
+	if err != nil {
+		return ProgressEvent{}, err
+	}
+	text := strings.TrimSpace(event.Text + " " + event.StatusText)
+	decoded.StateType, decoded.Desc = readState(text)
+	if decoded.StateType == "" {
+		if event.Status == progress.Error {
+			decoded.StateType = Error
+		}
+		decoded.Desc = text
+	}
+	decoded.Desc = strings.TrimSpace(decoded.Desc)
+	if decoded.ResourceType == Layer {
+		decoded.Parent = event.ParentID
+		decoded.Current, decoded.Total = event.Current, event.Total
+	}
+	return ProgressEvent{
+		ComposeOutputLine: decoded,
+		ID:                id,
+		Text:              text,
+	}, nil
+}
+
+type progressKey struct{}
+
+// WithProgress returns a new context which carries fn.
+// Calling ComposeService methods with the returned context,
+// fn is called with every progress event decoded while the call is running.
+//
+// fn is called synchronously in order of emission, even across stdout and stderr of compose.
+// After ctx is cancelled, fn is no longer called.
+// Since fn blocks the progress writer of compose, fn should return quickly.
+// fn must not call methods of the ComposeService which is emitting events.
+func WithProgress(ctx context.Context, fn func(event ProgressEvent)) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// progressNotifier delivers events to a function set by WithProgress.
+// It is shared between stdout and stderr so that events are serialized.
+type progressNotifier struct {
+	mu  sync.Mutex
+	ctx context.Context
+	fn  func(event ProgressEvent)
+}
+
+func (n *progressNotifier) set(ctx context.Context) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	fn, _ := ctx.Value(progressKey{}).(func(event ProgressEvent))
+	n.ctx = ctx
+	n.fn = fn
+}
+
+func (n *progressNotifier) reset() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.ctx = nil
+	n.fn = nil
+}
+
+func (n *progressNotifier) notify(event ProgressEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.fn == nil || n.ctx.Err() != nil {
+		return
+	}
+	n.fn(event)
+}
+
+// progressWriter is an io.Writer which decodes each line into ProgressEvent as it is written.
+// Lines which can not be decoded are only kept in the raw output.
+type progressWriter struct {
+	mu       sync.Mutex
+	raw      bytes.Buffer
+	partial  []byte
+	events   []ProgressEvent
+	decode   func(line string) (ProgressEvent, error)
+	notifier *progressNotifier
+}
+
+func newProgressWriter(decode func(line string) (ProgressEvent, error), notifier *progressNotifier) *progressWriter {
+	return &progressWriter{
+		decode:   decode,
+		notifier: notifier,
+	}
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := time.Now()
+	w.raw.Write(p)
+	w.partial = append(w.partial, p...)
+	for {
+		idx := bytes.IndexByte(w.partial, '\n')
+		if idx < 0 {
+			break
+		}
+		w.decodeLine(string(w.partial[:idx]), now)
+		w.partial = w.partial[idx+1:]
+	}
+	return len(p), nil
+}
+
+func (w *progressWriter) decodeLine(line string, t time.Time) {
+	if line == "" {
+		return
+	}
+	event, err := w.decode(line)
+	if err != nil {
+		return
+	}
+	event.Time = t
+	w.addEvent(event)
+}
+
+func (w *progressWriter) addEvent(event ProgressEvent) {
+	w.events = append(w.events, event)
+	if w.notifier != nil {
+		w.notifier.notify(event)
+	}
+}
+
+// writeEvent records event decoded from a typed progress.Event, along with line, its plain text form.
+func (w *progressWriter) writeEvent(line string, event ProgressEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.raw.WriteString(line)
+	event.Time = time.Now()
+	w.addEvent(event)
+}
+
+// writeRaw records line only in the raw output.
+func (w *progressWriter) writeRaw(line string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.raw.WriteString(line)
+}
+
+// flush decodes an incomplete trailing line if any.
+func (w *progressWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.decodeLine(string(w.partial), time.Now())
+	w.partial = nil
+}
+
+func (w *progressWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.raw.String()
+}
+
+func (w *progressWriter) Events() []ProgressEvent {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	out := make([]ProgressEvent, len(w.events))
+	copy(out, w.events)
+	return out
+}
+
+func (w *progressWriter) Reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.raw.Reset()
+	w.partial = nil
+	w.events = nil
+}
+
+// mergeEvents merges events from multiple streams in order of their time.
+func mergeEvents(eventsList ...[]ProgressEvent) []ProgressEvent {
+	var merged []ProgressEvent
+	for _, events := range eventsList {
+		merged = append(merged, events...)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Time.Before(merged[j].Time)
+	})
+	return merged
+}
+
+// eventWriter is a progress.Writer which records typed events into a progressWriter.
+// It prints events as compose's plain writer does, so that they are also found in the raw output.
+type eventWriter struct {
+	w      *progressWriter
+	decode func(event progress.Event) (ProgressEvent, error)
+	dryRun bool
+}
+
+var _ progress.Writer = (*eventWriter)(nil)
+
+func (w *eventWriter) Start(ctx context.Context) error { return nil }
+func (w *eventWriter) Stop()                           {}
+
+func (w *eventWriter) Event(e progress.Event) {
+	prefix := ""
+	if w.dryRun {
+		prefix = api.DRYRUN_PREFIX
+	}
+	line := fmt.Sprintln(prefix, e.ID, e.Text, e.StatusText)
+	event, err := w.decode(e)
+	if err != nil {
+		w.w.writeRaw(line)
+		return
+	}
+	w.w.writeEvent(line, event)
+}
+
+func (w *eventWriter) Events(events []progress.Event) {
+	for _, e := range events {
+		w.Event(e)
+	}
+}
+
+func (w *eventWriter) TailMsgf(msg string, args ...interface{}) {
+	msg = fmt.Sprintf(msg, args...)
+	if w.dryRun {
+		msg = api.DRYRUN_PREFIX + msg
+	}
+	w.w.writeRaw(msg + "\n")
+}
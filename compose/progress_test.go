@@ -0,0 +1,207 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/docker/compose/v2/pkg/progress"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/stretchr/testify/assert"
+)
+
+const prefixedServiceNameComposeYaml = `services:
+  web:
+    image: ubuntu:jammy-20230624
+  web-admin:
+    image: ubuntu:jammy-20230624
+  named:
+    image: ubuntu:jammy-20230624
+    container_name: custom-name
+`
+
+func TestProgressWriter(t *testing.T) {
+	assert := assert.New(t)
+
+	project := loadFromString(prefixedServiceNameComposeYaml)
+	w := newProgressWriter(func(line string) (ProgressEvent, error) {
+		return DecodeProgressEvent(line, "example_compose", project, false)
+	}, nil)
+
+	// plain progress writer emits a line by a single write, but this must not rely on it.
+	_, _ = fmt.Fprint(w, " Container example_compose-web-admin-1  Crea")
+	_, _ = fmt.Fprint(w, "ting\n Container example_compose-web-1  Creating\n")
+	_, _ = fmt.Fprintln(w, "", "Container custom-name", "", "Created")
+	_, _ = fmt.Fprintln(w, "", "Container example_compose-web-2", "", "Frobnicated")
+	_, _ = fmt.Fprintln(w, "unknown line")
+	_, _ = fmt.Fprint(w, " Container example_compose-web-1  Created")
+	w.flush()
+
+	expected := []ProgressEvent{
+		{
+			ComposeOutputLine: ComposeOutputLine{ResourceType: Container, Name: "web-admin", Num: 1, StateType: Creating},
+			ID:                "Container example_compose-web-admin-1",
+			Text:              "Creating",
+		},
+		{
+			ComposeOutputLine: ComposeOutputLine{ResourceType: Container, Name: "web", Num: 1, StateType: Creating},
+			ID:                "Container example_compose-web-1",
+			Text:              "Creating",
+		},
+		{
+			ComposeOutputLine: ComposeOutputLine{ResourceType: Container, Name: "named", Num: 1, StateType: Created},
+			ID:                "Container custom-name",
+			Text:              "Created",
+		},
+		{
+			ComposeOutputLine: ComposeOutputLine{ResourceType: Container, Name: "web", Num: 2, Desc: "Frobnicated"},
+			ID:                "Container example_compose-web-2",
+			Text:              "Frobnicated",
+		},
+		{
+			ComposeOutputLine: ComposeOutputLine{ResourceType: Container, Name: "web", Num: 1, StateType: Created},
+			ID:                "Container example_compose-web-1",
+			Text:              "Created",
+		},
+	}
+
+	events := w.Events()
+	for _, e := range events {
+		assert.False(e.Time.IsZero())
+	}
+	if diff := cmp.Diff(expected, events, cmpopts.IgnoreFields(ProgressEvent{}, "Time")); diff != "" {
+		t.Errorf("not equal. diff = %s", diff)
+	}
+
+	var out ComposeOutput
+	for _, e := range events {
+		out.AddEvent(e)
+	}
+	assert.Len(out.Events, 5)
+	assert.Equal(Created, out.Resource["Container:web"].StateType)
+	assert.Equal(Creating, out.Resource["Container:web-admin"].StateType)
+
+	w.Reset()
+	assert.Len(w.Events(), 0)
+	assert.Equal("", w.String())
+}
+
+func TestProgressWriter_notify(t *testing.T) {
+	assert := assert.New(t)
+
+	project := loadFromString(prefixedServiceNameComposeYaml)
+	decode := func(line string) (ProgressEvent, error) {
+		return DecodeProgressEvent(line, "example_compose", project, false)
+	}
+	notifier := &progressNotifier{}
+	out, err := newProgressWriter(decode, notifier), newProgressWriter(decode, notifier)
+
+	var received []string
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	notifier.set(WithProgress(ctx, func(event ProgressEvent) {
+		received = append(received, event.ID+" "+string(event.StateType))
+	}))
+
+	_, _ = fmt.Fprintln(out, " Container example_compose-web-1  Creating")
+	_, _ = fmt.Fprintln(err, " Container example_compose-web-2  Creating")
+	_, _ = fmt.Fprintln(out, " Container example_compose-web-1  Created")
+	cancel()
+	_, _ = fmt.Fprintln(err, " Container example_compose-web-2  Created")
+
+	if diff := cmp.Diff(
+		[]string{
+			"Container example_compose-web-1 Creating",
+			"Container example_compose-web-2 Creating",
+			"Container example_compose-web-1 Created",
+		},
+		received,
+	); diff != "" {
+		t.Errorf("not equal. diff = %s", diff)
+	}
+	// events are still recorded after cancellation.
+	assert.Len(mergeEvents(out.Events(), err.Events()), 4)
+
+	notifier.reset()
+	_, _ = fmt.Fprintln(out, " Container example_compose-web-1  Starting")
+	assert.Len(received, 3)
+}
+
+func TestEventWriter(t *testing.T) {
+	assert := assert.New(t)
+
+	project := loadFromString(prefixedServiceNameComposeYaml)
+	raw := newProgressWriter(nil, nil)
+	var w progress.Writer = &eventWriter{
+		w: raw,
+		decode: func(event progress.Event) (ProgressEvent, error) {
+			return decodeEvent(event, "example_compose", project, false)
+		},
+	}
+
+	w.Event(progress.StoppingEvent("Container example_compose-web-admin-1"))
+	w.Events([]progress.Event{
+		progress.ErrorMessageEvent("Container custom-name", "Error while Removing"),
+		progress.NewEvent("Container example_compose-web-2", progress.Working, "Frobnicating"),
+		progress.ErrorMessageEvent("Container example_compose-web-3", "failed"),
+		{ID: "3f4ca61aafcd", ParentID: "web", Text: "Downloading", Current: 1024, Total: 2048, Status: progress.Working},
+		progress.NewEvent("Container unknown-1", progress.Done, "Stopped"),
+	})
+	w.TailMsgf("done in %d", 1)
+
+	expected := []ProgressEvent{
+		{
+			ComposeOutputLine: ComposeOutputLine{ResourceType: Container, Name: "web-admin", Num: 1, StateType: Stopping},
+			ID:                "Container example_compose-web-admin-1",
+			Text:              "Stopping",
+		},
+		{
+			ComposeOutputLine: ComposeOutputLine{ResourceType: Container, Name: "named", Num: 1, StateType: Error, Desc: "while Removing"},
+			ID:                "Container custom-name",
+			Text:              "Error while Removing",
+		},
+		{
+			ComposeOutputLine: ComposeOutputLine{ResourceType: Container, Name: "web", Num: 2, Desc: "Frobnicating"},
+			ID:                "Container example_compose-web-2",
+			Text:              "Frobnicating",
+		},
+		{
+			// the state is taken from Status if the text does not tell it.
+			ComposeOutputLine: ComposeOutputLine{ResourceType: Container, Name: "web", Num: 3, StateType: Error, Desc: "failed"},
+			ID:                "Container example_compose-web-3",
+			Text:              "failed",
+		},
+		{
+			ComposeOutputLine: ComposeOutputLine{ResourceType: Layer, Name: "3f4ca61aafcd", Parent: "web", StateType: Downloading, Current: 1024, Total: 2048},
+			ID:                "3f4ca61aafcd",
+			Text:              "Downloading",
+		},
+	}
+	events := raw.Events()
+	for _, e := range events {
+		assert.False(e.Time.IsZero())
+	}
+	if diff := cmp.Diff(expected, events, cmpopts.IgnoreFields(ProgressEvent{}, "Time")); diff != "" {
+		t.Errorf("not equal. diff = %s", diff)
+	}
+	// every event is printed as compose's plain writer does,
+	// though decoding the raw output loses Status, ParentID and progress of layers.
+	assert.Contains(raw.String(), " Container unknown-1  Stopped\n")
+	assert.Contains(raw.String(), "done in 1\n")
+	var out ComposeOutput
+	out.ParseOutput("", raw.String(), "example_compose", project, false)
+	assert.Len(out.Events, len(expected))
+	for i, e := range out.Events {
+		assert.Equal(expected[i].Key(), e.Key())
+		assert.Equal(expected[i].Text, e.Text)
+	}
+}
+
+func TestDecodeProgressEvent_nil_project(t *testing.T) {
+	_, err := DecodeProgressEvent(" Container example_compose-web-1  Created", "example_compose", nil, false)
+	assert.Error(t, err)
+	var out ComposeOutput
+	out.ParseOutput(" Container example_compose-web-1  Created\n", "", "example_compose", nil, false)
+	assert.Empty(t, out.Events)
+}
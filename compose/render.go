@@ -0,0 +1,141 @@
+package compose
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/distribution/reference"
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/compose/v2/pkg/compose"
+	godigest "github.com/opencontainers/go-digest"
+)
+
+// RenderFormat is the output format of RenderConfig.
+type RenderFormat string
+
+const (
+	RenderYAML RenderFormat = "yaml"
+	RenderJSON RenderFormat = "json"
+)
+
+// RedactedValue replaces values of environment variables when RenderOptions.RedactEnvironment is true.
+const RedactedValue = "********"
+
+// RenderOptions is options for RenderConfig.
+type RenderOptions struct {
+	// Format defaults to RenderYAML.
+	Format RenderFormat
+	// ResolveImageDigests pins images of services to digests queried from registries, as `compose config --resolve-image-digests` does.
+	// It requires a docker cli.
+	ResolveImageDigests bool
+	// RedactEnvironment replaces values of environment variables of services with RedactedValue.
+	// Variables without value are left as is.
+	RedactEnvironment bool
+	// NoInterpolate must be set if the project is loaded with interpolation skipped.
+	// Otherwise "$" in the output is escaped as "$$", so that loading it again yields the same project.
+	NoInterpolate bool
+	// Hash computes the config hash of each service, as `compose config --hash` does.
+	Hash bool
+}
+
+// RenderedConfig is the result of RenderConfig.
+type RenderedConfig struct {
+	Content []byte
+	// Hashes maps service names to their config hashes. It is nil unless RenderOptions.Hash is true.
+	// The hash is what compose labels containers with, which is used to decide recreation of them.
+	Hashes map[string]string
+}
+
+// RenderConfig serializes project into the canonical form, equivalent to `docker compose config`.
+// project is not mutated.
+//
+// dockerCli is used only when options.ResolveImageDigests is true and can be nil otherwise.
+func RenderConfig(ctx context.Context, project *types.Project, dockerCli command.Cli, options RenderOptions) (RenderedConfig, error) {
+	project = cloneServices(project)
+
+	var rendered RenderedConfig
+	if options.Hash {
+		rendered.Hashes = make(map[string]string, len(project.Services))
+		for _, service := range project.Services {
+			hash, err := serviceHash(service)
+			if err != nil {
+				return RenderedConfig{}, err
+			}
+			rendered.Hashes[service.Name] = hash
+		}
+	}
+
+	if options.ResolveImageDigests {
+		if dockerCli == nil {
+			return RenderedConfig{}, fmt.Errorf("dockerCli must not be nil to resolve image digests")
+		}
+		if err := project.ResolveImages(imageDigestResolver(ctx, dockerCli)); err != nil {
+			return RenderedConfig{}, err
+		}
+	}
+
+	if options.RedactEnvironment {
+		for i, service := range project.Services {
+			project.Services[i].Environment = redactEnvironment(service.Environment)
+		}
+	}
+
+	var err error
+	switch options.Format {
+	case RenderYAML, "":
+		rendered.Content, err = project.MarshalYAML()
+	case RenderJSON:
+		rendered.Content, err = project.MarshalJSON()
+	default:
+		return RenderedConfig{}, fmt.Errorf("unsupported format %q", options.Format)
+	}
+	if err != nil {
+		return RenderedConfig{}, err
+	}
+
+	if !options.NoInterpolate {
+		rendered.Content = bytes.ReplaceAll(rendered.Content, []byte{'$'}, []byte{'$', '$'})
+	}
+	return rendered, nil
+}
+
+// Mimicking Config of compose.
+func imageDigestResolver(ctx context.Context, dockerCli command.Cli) func(named reference.Named) (godigest.Digest, error) {
+	return func(named reference.Named) (godigest.Digest, error) {
+		auth, err := command.RetrieveAuthTokenFromImage(ctx, dockerCli, named.String())
+		if err != nil {
+			return "", err
+		}
+		inspect, err := dockerCli.Client().DistributionInspect(ctx, named.String(), auth)
+		if err != nil {
+			return "", err
+		}
+		return inspect.Descriptor.Digest, nil
+	}
+}
+
+func redactEnvironment(env types.MappingWithEquals) types.MappingWithEquals {
+	if env == nil {
+		return nil
+	}
+	redacted := maps.Clone(env)
+	for k, v := range redacted {
+		if v != nil {
+			r := RedactedValue
+			redacted[k] = &r
+		}
+	}
+	return redacted
+}
+
+// serviceHash is compose.ServiceHash without overwriting deploy.replicas of service.
+func serviceHash(service types.ServiceConfig) (string, error) {
+	if service.Deploy != nil {
+		deploy := *service.Deploy
+		service.Deploy = &deploy
+	}
+	return compose.ServiceHash(service)
+}
@@ -0,0 +1,65 @@
+package compose
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const renderComposeYaml = `services:
+  web:
+    image: ubuntu:jammy-20230624
+    environment:
+      PASSWORD: secret
+      PRICE: $$5
+    deploy:
+      replicas: 3
+`
+
+func TestRenderConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	project := loadFromString(renderComposeYaml)
+
+	rendered, err := RenderConfig(context.Background(), project, nil, RenderOptions{Hash: true})
+	assert.NoError(err)
+	assert.Contains(string(rendered.Content), "PASSWORD: secret")
+	assert.Contains(string(rendered.Content), "PRICE: $$5")
+	assert.Contains(string(rendered.Content), "replicas: 3")
+	assert.Len(rendered.Hashes["web"], 64)
+
+	// Rendered content is loaded to the same project.
+	reloaded := loadFromString(string(rendered.Content))
+	web, _ := reloaded.GetService("web")
+	assert.Equal("$5", *web.Environment["PRICE"])
+	assert.Equal(uint64(3), *web.Deploy.Replicas)
+
+	reRendered, err := RenderConfig(context.Background(), reloaded, nil, RenderOptions{Hash: true})
+	assert.NoError(err)
+	assert.Equal(rendered.Hashes, reRendered.Hashes)
+
+	rendered, err = RenderConfig(context.Background(), project, nil, RenderOptions{Format: RenderJSON, RedactEnvironment: true})
+	assert.NoError(err)
+	assert.Nil(rendered.Hashes)
+	var decoded struct {
+		Services map[string]struct {
+			Environment map[string]string
+		}
+	}
+	assert.NoError(json.Unmarshal(rendered.Content, &decoded))
+	assert.False(strings.Contains(string(rendered.Content), "secret"))
+	assert.Equal(RedactedValue, decoded.Services["web"].Environment["PASSWORD"])
+
+	// project is not mutated.
+	web, _ = project.GetService("web")
+	assert.Equal("secret", *web.Environment["PASSWORD"])
+	assert.Equal(uint64(3), *web.Deploy.Replicas)
+
+	_, err = RenderConfig(context.Background(), project, nil, RenderOptions{ResolveImageDigests: true})
+	assert.Error(err)
+	_, err = RenderConfig(context.Background(), project, nil, RenderOptions{Format: "toml"})
+	assert.Error(err)
+}
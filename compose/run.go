@@ -0,0 +1,322 @@
+package compose
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/docker/compose/v2/pkg/api"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/docker/pkg/stringid"
+)
+
+// RunResult is the result of ComposeService.Run.
+type RunResult struct {
+	ContainerID   string
+	ContainerName string
+	// ExitCode is the exit code of the one-off container. It is always 0 if detached.
+	ExitCode int
+	// Stdout and Stderr are outputs of the one-off container.
+	// Both are empty if detached. Stderr is also empty if Tty is true, since the tty merges stderr into stdout.
+	Stdout, Stderr string
+	// Output is the progress of starting dependencies and creating the one-off container.
+	Output ComposeOutput
+}
+
+// Run executes the equivalent to a `compose run`
+//
+// Unlike RunOneOffContainer of compose, Run never touches process-wide signal handling.
+// Signals are not forwarded to the container.
+// If ctx is cancelled while waiting for the container, Run stops it.
+//
+// Run creates a container labeled with api.OneoffLabel=True through the same path as Create,
+// then starts it. If options.Detach is false, Run attaches to the container and waits for it to exit.
+// Unless options.NoDeps is true, dependencies of options.Service are created and started before that,
+// and Run waits for them to be running or healthy.
+//
+// If options.AutoRemove is true, Run removes the container after it exits, or if attaching or starting it fails.
+// Thus AutoRemove can not be combined with Detach.
+// options.Interactive is not supported, since compose creates containers without closing stdin on detach.
+// options.UseNetworkAliases and options.Index are ignored.
+// options.Project defaults to the wrapped project.
+//
+// In dry run mode, Run returns just after the container is created.
+// The dry run view returns ErrDryRunUnsupported if options.Build is non nil.
+//
+// Run waits for other calls mutating the service and its dependencies only until the container is created.
+func (s *ComposeService) Run(ctx context.Context, options api.RunOptions) (RunResult, error) {
+	if options.Interactive {
+		return RunResult{}, fmt.Errorf("Interactive is not supported")
+	}
+	if options.AutoRemove && options.Detach {
+		return RunResult{}, fmt.Errorf("AutoRemove can not be combined with Detach")
+	}
+
+	result, apiClient, dryRun, err := s.createOneOff(ctx, options)
+	if err != nil || dryRun {
+		return result, err
+	}
+
+	if options.Detach {
+		return result, apiClient.ContainerStart(ctx, result.ContainerID, dockertypes.ContainerStartOptions{})
+	}
+
+	result.ExitCode, result.Stdout, result.Stderr, err = runOneOff(ctx, apiClient, result.ContainerID, options.Tty, options.AutoRemove)
+	return result, err
+}
+
+// runClient is the subset of client.APIClient used by runOneOff.
+type runClient interface {
+	ContainerAttach(ctx context.Context, container string, options dockertypes.ContainerAttachOptions) (dockertypes.HijackedResponse, error)
+	ContainerWait(ctx context.Context, container string, condition container.WaitCondition) (<-chan container.WaitResponse, <-chan error)
+	ContainerStart(ctx context.Context, container string, options dockertypes.ContainerStartOptions) error
+	ContainerStop(ctx context.Context, container string, options container.StopOptions) error
+	ContainerRemove(ctx context.Context, container string, options dockertypes.ContainerRemoveOptions) error
+}
+
+// runOneOff attaches to the created container, starts it and waits for it to exit.
+// If ctx is cancelled, the container is stopped.
+// If autoRemove is true, the container is removed whether it ran or not.
+func runOneOff(
+	ctx context.Context,
+	apiClient runClient,
+	containerID string,
+	tty bool,
+	autoRemove bool,
+) (exitCode int, stdout, stderr string, err error) {
+	defer func() {
+		if !autoRemove {
+			return
+		}
+		removeErr := apiClient.ContainerRemove(context.Background(), containerID, dockertypes.ContainerRemoveOptions{Force: true})
+		if err == nil {
+			err = removeErr
+		}
+	}()
+
+	resp, err := apiClient.ContainerAttach(ctx, containerID, dockertypes.ContainerAttachOptions{
+		Stream: true,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		return 0, "", "", err
+	}
+	defer resp.Close()
+
+	waitC, errC := apiClient.ContainerWait(ctx, containerID, container.WaitConditionNextExit)
+
+	var outBuf, errBuf bytes.Buffer
+	copyDone := make(chan error, 1)
+	go func() {
+		var err error
+		if tty {
+			_, err = io.Copy(&outBuf, resp.Reader)
+		} else {
+			_, err = stdcopy.StdCopy(&outBuf, &errBuf, resp.Reader)
+		}
+		copyDone <- err
+	}()
+
+	if err := apiClient.ContainerStart(ctx, containerID, dockertypes.ContainerStartOptions{}); err != nil {
+		resp.Close()
+		<-copyDone
+		return 0, "", "", err
+	}
+
+	exited := false
+	select {
+	case waited := <-waitC:
+		exited = true
+		if waited.Error != nil {
+			err = fmt.Errorf("waiting container: %s", waited.Error.Message)
+		}
+		exitCode = int(waited.StatusCode)
+	case err = <-errC:
+	}
+	if ctx.Err() != nil {
+		_ = apiClient.ContainerStop(context.Background(), containerID, container.StopOptions{})
+		err = ctx.Err()
+	}
+	if exited && err == nil {
+		// The stream ends when the container exits.
+		err = <-copyDone
+	} else {
+		// The container may still be running, thus the stream is closed to stop copying.
+		resp.Close()
+		<-copyDone
+	}
+	// Buffers are read only after the copying goroutine has returned.
+	return exitCode, outBuf.String(), errBuf.String(), err
+}
+
+func (s *ComposeService) createOneOff(ctx context.Context, options api.RunOptions) (result RunResult, apiClient client.APIClient, dryRun bool, err error) {
+	c, ctx := s.begin(ctx)
+	defer c.end()
+
+	apiClient = c.cli.Client()
+	dryRun = c.dryRun
+
+	if err := c.rejectBuild(options.Build); err != nil {
+		return RunResult{}, apiClient, dryRun, err
+	}
+	if err := c.lock(ctx, []string{options.Service}, !options.NoDeps, projectResources); err != nil {
+		return RunResult{}, apiClient, dryRun, err
+	}
+	if options.Project == nil {
+		options.Project = c.project
+	}
+	project := options.Project
+
+	service, err := project.GetService(options.Service)
+	if err != nil {
+		return RunResult{}, apiClient, dryRun, err
+	}
+
+	if !options.NoDeps {
+		var dependencies []string
+		err := project.WithServices([]string{service.Name}, func(sc types.ServiceConfig) error {
+			if sc.Name != service.Name {
+				dependencies = append(dependencies, sc.Name)
+			}
+			return nil
+		})
+		if err != nil {
+			return RunResult{}, apiClient, dryRun, err
+		}
+		if len(dependencies) > 0 {
+			project, err := forServices(project, dependencies, true)
+			if err != nil {
+				return RunResult{}, apiClient, dryRun, err
+			}
+			err = c.service.Create(ctx, cloneServices(project), api.CreateOptions{
+				Build:     options.Build,
+				Services:  dependencies,
+				QuietPull: options.QuietPull,
+			})
+			if err != nil {
+				return RunResult{Output: c.parseOutput()}, apiClient, dryRun, err
+			}
+			err = c.service.Start(ctx, s.projectName, api.StartOptions{
+				Project:  project,
+				Services: dependencies,
+				Wait:     true,
+			})
+			if err != nil {
+				return RunResult{Output: c.parseOutput()}, apiClient, dryRun, err
+			}
+		}
+	}
+
+	oneOffProject, oneOff := toOneOffProject(project, service, options)
+	err = c.service.Create(ctx, oneOffProject, api.CreateOptions{
+		Build:         options.Build,
+		Services:      []string{oneOff.Name},
+		IgnoreOrphans: true,
+		QuietPull:     options.QuietPull,
+	})
+	result = RunResult{
+		ContainerName: oneOff.ContainerName,
+		Output:        c.parseOutput(),
+	}
+	if err != nil {
+		return result, apiClient, dryRun, err
+	}
+
+	inspected, err := apiClient.ContainerInspect(ctx, oneOff.ContainerName)
+	if err != nil {
+		return result, apiClient, dryRun, err
+	}
+	result.ContainerID = inspected.ID
+	return result, apiClient, dryRun, nil
+}
+
+// toOneOffProject returns a copy of project which only has a one-off service derived from service.
+//
+// The one-off service is renamed so that compose would not converge existing containers of service into it.
+// Labels still point to the original service, as `compose run` does.
+func toOneOffProject(project *types.Project, service types.ServiceConfig, options api.RunOptions) (*types.Project, types.ServiceConfig) {
+	slug := stringid.GenerateRandomID()
+	truncated := stringid.TruncateID(slug)
+
+	oneOff := service
+	oneOff.Name = service.Name + api.Separator + "run" + api.Separator + truncated
+	if oneOff.Image == "" {
+		oneOff.Image = api.GetImageNameOrDefault(service, project.Name)
+	}
+
+	oneOff.ContainerName = options.Name
+	if oneOff.ContainerName == "" {
+		oneOff.ContainerName = project.Name + api.Separator + oneOff.Name
+	}
+	oneOff.Scale = 1
+	oneOff.Restart = ""
+	if service.Deploy != nil {
+		deploy := *service.Deploy
+		deploy.RestartPolicy = nil
+		oneOff.Deploy = &deploy
+	}
+	oneOff.Tty = options.Tty
+	oneOff.StdinOpen = false
+
+	// Mimicking applyRunOptions of compose.
+	if len(options.Command) > 0 {
+		oneOff.Command = options.Command
+	}
+	if options.Entrypoint != nil {
+		oneOff.Entrypoint = options.Entrypoint
+		if len(options.Command) == 0 {
+			oneOff.Command = []string{}
+		}
+	}
+	if options.User != "" {
+		oneOff.User = options.User
+	}
+	if options.WorkingDir != "" {
+		oneOff.WorkingDir = options.WorkingDir
+	}
+	if options.Privileged {
+		oneOff.Privileged = true
+	}
+	if len(options.CapAdd) > 0 {
+		oneOff.CapAdd = append(slices.Clone(oneOff.CapAdd), options.CapAdd...)
+		oneOff.CapDrop = slices.DeleteFunc(slices.Clone(oneOff.CapDrop), func(c string) bool {
+			return slices.Contains(options.CapAdd, c)
+		})
+	}
+	if len(options.CapDrop) > 0 {
+		oneOff.CapDrop = append(slices.Clone(oneOff.CapDrop), options.CapDrop...)
+		oneOff.CapAdd = slices.DeleteFunc(slices.Clone(oneOff.CapAdd), func(c string) bool {
+			return slices.Contains(options.CapDrop, c)
+		})
+	}
+	oneOff.Environment = maps.Clone(service.Environment)
+	if len(options.Environment) > 0 {
+		if oneOff.Environment == nil {
+			oneOff.Environment = types.MappingWithEquals{}
+		}
+		env := types.NewMappingWithEquals(options.Environment).
+			Resolve(project.Environment.Resolve).
+			RemoveEmpty()
+		oneOff.Environment.OverrideBy(env)
+	}
+	oneOff.Labels = maps.Clone(service.Labels)
+	for k, v := range options.Labels {
+		oneOff.Labels = oneOff.Labels.Add(k, v)
+	}
+	oneOff.CustomLabels = maps.Clone(service.CustomLabels).
+		Add(api.SlugLabel, slug).
+		Add(api.OneoffLabel, "True")
+
+	oneOffProject := *project
+	oneOffProject.Services = types.Services{oneOff}
+	oneOffProject.DisabledServices = nil
+	return &oneOffProject, oneOff
+}
@@ -0,0 +1,202 @@
+package compose
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/docker/compose/v2/pkg/api"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const runComposeYaml = `services:
+  web:
+    image: ubuntu:jammy-20230624
+    restart: always
+    environment:
+      FOO: foo
+    labels:
+      label: value
+    cap_add:
+      - NET_ADMIN
+  named:
+    image: ubuntu:jammy-20230624
+    container_name: custom-name
+`
+
+func TestToOneOffProject(t *testing.T) {
+	assert := assert.New(t)
+
+	project := loadFromString(runComposeYaml)
+	AddDockerComposeLabel(project)
+	service, err := project.GetService("web")
+	if err != nil {
+		panic(err)
+	}
+
+	oneOffProject, oneOff := toOneOffProject(project, service, api.RunOptions{
+		Service:     "web",
+		Command:     []string{"echo", "bar"},
+		Environment: []string{"BAR=bar", "COMPOSE_WRAPPER_UNSET"},
+		Labels:      types.Labels{"run": "yes"},
+		CapDrop:     []string{"NET_ADMIN"},
+	})
+
+	assert.Len(oneOffProject.Services, 1)
+	assert.Equal(oneOff.Name, oneOffProject.Services[0].Name)
+	assert.True(strings.HasPrefix(oneOff.Name, "web-run-"))
+	assert.Equal("example_compose-"+oneOff.Name, oneOff.ContainerName)
+	assert.True(isTruncatedID(strings.TrimPrefix(oneOff.Name, "web-run-")))
+	assert.Equal(1, oneOff.Scale)
+	assert.Equal("", oneOff.Restart)
+	assert.Equal(types.ShellCommand{"echo", "bar"}, oneOff.Command)
+	assert.Empty(oneOff.CapAdd)
+	assert.Equal([]string{"NET_ADMIN"}, oneOff.CapDrop)
+
+	bar := "bar"
+	assert.Equal(&bar, oneOff.Environment["BAR"])
+	_, ok := oneOff.Environment["COMPOSE_WRAPPER_UNSET"]
+	assert.False(ok)
+	assert.Equal("yes", oneOff.Labels["run"])
+	assert.Equal("True", oneOff.CustomLabels[api.OneoffLabel])
+	assert.Equal("web", oneOff.CustomLabels[api.ServiceLabel])
+	assert.NotEmpty(oneOff.CustomLabels[api.SlugLabel])
+
+	// The original project must be left untouched.
+	service, _ = project.GetService("web")
+	assert.Len(project.Services, 2)
+	assert.Equal("always", service.Restart)
+	assert.Equal([]string{"NET_ADMIN"}, service.CapAdd)
+	assert.Empty(service.CapDrop)
+	_, ok = service.Environment["BAR"]
+	assert.False(ok)
+	_, ok = service.Labels["run"]
+	assert.False(ok)
+	assert.Equal("False", service.CustomLabels[api.OneoffLabel])
+
+	// One-off containers are resolved to the original service.
+	name, num, ok := resolveContainerName(oneOff.ContainerName, "example_compose", project)
+	assert.True(ok)
+	assert.Equal("web", name)
+	assert.Equal(0, num)
+
+	named, _ := project.GetService("named")
+	_, oneOff = toOneOffProject(project, named, api.RunOptions{Service: "named", Name: "given"})
+	assert.Equal("given", oneOff.ContainerName)
+}
+
+// fakeRunClient simulates a one-off container whose output is written through a pipe.
+type fakeRunClient struct {
+	// attached receives the container side of the attached stream.
+	attached chan net.Conn
+	waitC    chan container.WaitResponse
+	errC     chan error
+	startErr error
+
+	mu      sync.Mutex
+	stopped bool
+	removed bool
+}
+
+func newFakeRunClient() *fakeRunClient {
+	return &fakeRunClient{
+		attached: make(chan net.Conn, 1),
+		waitC:    make(chan container.WaitResponse, 1),
+		errC:     make(chan error, 1),
+	}
+}
+
+func (c *fakeRunClient) ContainerAttach(ctx context.Context, id string, options dockertypes.ContainerAttachOptions) (dockertypes.HijackedResponse, error) {
+	client, container := net.Pipe()
+	c.attached <- container
+	return dockertypes.HijackedResponse{Conn: client, Reader: bufio.NewReader(client)}, nil
+}
+
+func (c *fakeRunClient) ContainerWait(ctx context.Context, id string, condition container.WaitCondition) (<-chan container.WaitResponse, <-chan error) {
+	go func() {
+		<-ctx.Done()
+		c.errC <- ctx.Err()
+	}()
+	return c.waitC, c.errC
+}
+
+func (c *fakeRunClient) ContainerStart(ctx context.Context, id string, options dockertypes.ContainerStartOptions) error {
+	return c.startErr
+}
+
+func (c *fakeRunClient) ContainerStop(ctx context.Context, id string, options container.StopOptions) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopped = true
+	return nil
+}
+
+func (c *fakeRunClient) ContainerRemove(ctx context.Context, id string, options dockertypes.ContainerRemoveOptions) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removed = true
+	return nil
+}
+
+func TestRunOneOff(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// the container exits after writing output.
+	client := newFakeRunClient()
+	go func() {
+		conn := <-client.attached
+		_, _ = stdcopy.NewStdWriter(conn, stdcopy.Stdout).Write([]byte("out\n"))
+		_, _ = stdcopy.NewStdWriter(conn, stdcopy.Stderr).Write([]byte("err\n"))
+		_ = conn.Close()
+		client.waitC <- container.WaitResponse{StatusCode: 3}
+	}()
+	exitCode, stdout, stderr, err := runOneOff(context.Background(), client, "id", false, true)
+	require.NoError(err)
+	assert.Equal(3, exitCode)
+	assert.Equal("out\n", stdout)
+	assert.Equal("err\n", stderr)
+	assert.True(client.removed)
+
+	// ctx is cancelled while the container keeps writing.
+	client = newFakeRunClient()
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		w := stdcopy.NewStdWriter(<-client.attached, stdcopy.Stdout)
+		for i := 0; ; i++ {
+			if _, err := w.Write([]byte("tick\n")); err != nil {
+				return
+			}
+			if i == 3 {
+				cancel()
+			}
+		}
+	}()
+	_, stdout, _, err = runOneOff(ctx, client, "id", false, false)
+	assert.ErrorIs(err, context.Canceled)
+	assert.True(strings.HasPrefix(stdout, "tick\ntick\ntick\ntick\n"))
+	assert.True(client.stopped)
+	assert.False(client.removed)
+
+	// waiting fails while the container is running.
+	client = newFakeRunClient()
+	client.errC <- errors.New("wait failed")
+	_, _, _, err = runOneOff(context.Background(), client, "id", true, false)
+	assert.EqualError(err, "wait failed")
+
+	// the created container is removed even if it can not be started.
+	client = newFakeRunClient()
+	client.startErr = errors.New("port is already allocated")
+	_, _, _, err = runOneOff(context.Background(), client, "id", false, true)
+	assert.EqualError(err, "port is already allocated")
+	assert.True(client.removed)
+}
@@ -0,0 +1,175 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/compose/v2/pkg/progress"
+	dockertypes "github.com/docker/docker/api/types"
+	containertypes "github.com/docker/docker/api/types/container"
+)
+
+// Scale changes replica counts of services and converges containers to them.
+// replicas maps service names to desired replica counts.
+//
+// Scale updates deploy.replicas of the wrapped project once containers are converged,
+// so later calls, e.g. Create and Up, keep the counts. The dry run view and failed calls leave the project as is.
+// Surplus replicas are stopped and removed, the highest replica number first,
+// while compose alone would keep the oldest containers regardless of their numbers.
+// Extra replicas are created and started. Since Start is called for services scaled up,
+// their stopped replicas are started as well.
+// Existing replicas are never recreated even if their config diverged, and other services are left as they are.
+//
+// The returned ComposeOutput has Stopping/Stopped/Removing/Removed lines for removed replicas
+// and Creating/Created/Starting/Started lines for added replicas.
+func (s *ComposeService) Scale(ctx context.Context, replicas map[string]int) (ComposeOutput, error) {
+	c, ctx := s.begin(ctx)
+	defer c.end()
+
+	if err := validateScale(c.project, replicas); err != nil {
+		return ComposeOutput{}, err
+	}
+	if err := c.lock(ctx, sortedKeys(replicas), false, projectResources); err != nil {
+		return ComposeOutput{}, err
+	}
+
+	var scaledUp []string
+	for _, name := range sortedKeys(replicas) {
+		// Ps is called before the project is updated, since it is filtered by the project.
+		containers, err := c.service.Ps(ctx, s.projectName, api.PsOptions{
+			Project:  c.project,
+			All:      true,
+			Services: []string{name},
+		})
+		if err != nil {
+			return c.parseOutput(), err
+		}
+		containers = slices.DeleteFunc(containers, func(c api.ContainerSummary) bool {
+			return c.Labels[api.OneoffLabel] == "True"
+		})
+		if surplus := scaleDownTargets(containers, replicas[name]); len(surplus) > 0 {
+			err := c.runWithProgress(ctx, func(ctx context.Context) error {
+				return c.removeReplicas(ctx, surplus)
+			})
+			if err != nil {
+				return c.parseOutput(), err
+			}
+		}
+		if len(containers) < replicas[name] {
+			scaledUp = append(scaledUp, name)
+		}
+	}
+
+	setReplicas(c.project, replicas)
+
+	if len(scaledUp) > 0 {
+		// compose would converge every service of the project given, not only ones scaled up.
+		project, err := forServices(c.project, scaledUp, false)
+		if err != nil {
+			return c.parseOutput(), err
+		}
+		err = c.service.Create(ctx, cloneServices(project), api.CreateOptions{
+			Services:             scaledUp,
+			Recreate:             api.RecreateNever,
+			RecreateDependencies: api.RecreateNever,
+			Inherit:              true,
+		})
+		if err != nil {
+			return c.parseOutput(), err
+		}
+		err = c.service.Start(ctx, s.projectName, api.StartOptions{
+			Project:  project,
+			Services: scaledUp,
+		})
+		if err != nil {
+			return c.parseOutput(), err
+		}
+	}
+
+	if !c.dryRun {
+		s.projectMu.Lock()
+		setReplicas(s.project, replicas)
+		s.projectMu.Unlock()
+	}
+	return c.parseOutput(), nil
+}
+
+func validateScale(project *types.Project, replicas map[string]int) error {
+	for name, count := range replicas {
+		service, err := project.GetService(name)
+		if err != nil {
+			return err
+		}
+		if count < 0 {
+			return fmt.Errorf("replicas of service %q must not be negative but is %d", name, count)
+		}
+		if count > 1 && service.ContainerName != "" {
+			return fmt.Errorf("service %q has container_name %q which prevents scaling to %d", name, service.ContainerName, count)
+		}
+	}
+	return nil
+}
+
+// scaleDownTargets returns containers to be removed to leave count replicas, the highest replica number first.
+func scaleDownTargets(containers []api.ContainerSummary, count int) []api.ContainerSummary {
+	if len(containers) <= count {
+		return nil
+	}
+	sorted := slices.Clone(containers)
+	slices.SortFunc(sorted, func(i, j api.ContainerSummary) int {
+		return replicaNumber(j) - replicaNumber(i)
+	})
+	return sorted[:len(sorted)-count]
+}
+
+// removeReplicas stops and removes containers in order, reporting progress like compose does.
+func (c *call) removeReplicas(ctx context.Context, containers []api.ContainerSummary) error {
+	w := progress.ContextWriter(ctx)
+	apiClient := c.cli.Client()
+	for _, container := range containers {
+		eventName := "Container " + container.Name
+		w.Event(progress.StoppingEvent(eventName))
+		if err := apiClient.ContainerStop(ctx, container.ID, containertypes.StopOptions{}); err != nil {
+			w.Event(progress.ErrorMessageEvent(eventName, "Error while Stopping"))
+			return err
+		}
+		w.Event(progress.StoppedEvent(eventName))
+		w.Event(progress.RemovingEvent(eventName))
+		if err := apiClient.ContainerRemove(ctx, container.ID, dockertypes.ContainerRemoveOptions{}); err != nil {
+			w.Event(progress.ErrorMessageEvent(eventName, "Error while Removing"))
+			return err
+		}
+		w.Event(progress.RemovedEvent(eventName))
+	}
+	return nil
+}
+
+func setReplicas(project *types.Project, replicas map[string]int) {
+	for i, service := range project.Services {
+		count, ok := replicas[service.Name]
+		if !ok {
+			continue
+		}
+		deploy := types.DeployConfig{}
+		if service.Deploy != nil {
+			deploy = *service.Deploy
+		}
+		r := uint64(count)
+		deploy.Replicas = &r
+		service.Deploy = &deploy
+		service.Scale = count
+		project.Services[i] = service
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,103 @@
+package compose
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/compose/v2/pkg/compose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScaleDownTargets(t *testing.T) {
+	var containers []api.ContainerSummary
+	for _, num := range []int{2, 4, 1, 3} {
+		containers = append(containers, api.ContainerSummary{
+			Name:   "example_compose-web-" + strconv.Itoa(num),
+			Labels: map[string]string{api.ContainerNumberLabel: strconv.Itoa(num)},
+		})
+	}
+
+	var names []string
+	for _, c := range scaleDownTargets(containers, 1) {
+		names = append(names, c.Name)
+	}
+	assert.Equal(t, []string{"example_compose-web-4", "example_compose-web-3", "example_compose-web-2"}, names)
+	assert.Empty(t, scaleDownTargets(containers, 4))
+	assert.Len(t, scaleDownTargets(containers, 0), 4)
+}
+
+func TestSetReplicas(t *testing.T) {
+	assert := assert.New(t)
+	project := loadFromString(prefixedServiceNameComposeYaml)
+
+	assert.NoError(validateScale(project, map[string]int{"web": 3, "named": 1}))
+	assert.Error(validateScale(project, map[string]int{"named": 2}))
+	assert.Error(validateScale(project, map[string]int{"web": -1}))
+	assert.Error(validateScale(project, map[string]int{"nonexistent": 1}))
+
+	setReplicas(project, map[string]int{"web": 3})
+	web, _ := project.GetService("web")
+	assert.Equal(uint64(3), *web.Deploy.Replicas)
+	assert.Equal(3, web.Scale)
+	admin, _ := project.GetService("web-admin")
+	if admin.Deploy != nil && admin.Deploy.Replicas != nil {
+		assert.Equal(uint64(1), *admin.Deploy.Replicas)
+	}
+
+	cloned := cloneServices(project)
+	clonedWeb, _ := cloned.GetService("web")
+	_, _ = compose.ServiceHash(clonedWeb)
+	web, _ = project.GetService("web")
+	assert.Equal(uint64(3), *web.Deploy.Replicas)
+}
+
+func TestComposeService_Scale(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	composeService := NewComposeService("example_compose", loadFromString(waitComposeYaml), nil)
+
+	var (
+		createErr        error
+		created, started []string
+		recreate         string
+	)
+	composeService.newService = func(command.Cli) api.Service {
+		return &api.ServiceProxy{
+			PsFn: func(ctx context.Context, projectName string, options api.PsOptions) ([]api.ContainerSummary, error) {
+				return []api.ContainerSummary{waitContainer("worker", 1, "running", "", 0)}, nil
+			},
+			CreateFn: func(ctx context.Context, project *types.Project, options api.CreateOptions) error {
+				created, recreate = project.ServiceNames(), options.Recreate
+				return createErr
+			},
+			StartFn: func(ctx context.Context, projectName string, options api.StartOptions) error {
+				started = options.Project.ServiceNames()
+				return nil
+			},
+		}
+	}
+	replicasOf := func() uint64 {
+		worker, err := composeService.project.GetService("worker")
+		require.NoError(err)
+		return *worker.Deploy.Replicas
+	}
+
+	_, err := composeService.Scale(context.Background(), map[string]int{"worker": 3})
+	require.NoError(err)
+	assert.Equal([]string{"worker"}, created)
+	assert.Equal([]string{"worker"}, started)
+	assert.Equal(api.RecreateNever, recreate)
+	assert.Equal(uint64(3), replicasOf())
+
+	// a failed call leaves the count as is.
+	createErr = errors.New("create failed")
+	_, err = composeService.Scale(context.Background(), map[string]int{"worker": 4})
+	assert.ErrorIs(err, createErr)
+	assert.Equal(uint64(3), replicasOf())
+}
@@ -0,0 +1,164 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/docker/compose/v2/pkg/api"
+)
+
+// ProcessTable is processes running in a container.
+type ProcessTable struct {
+	Service string
+	// Num is the replica number. It is 0 for one-off containers.
+	Num         int
+	Container   string
+	ContainerID string
+	// Titles are column names, e.g. "UID", "PID" and "CMD". They depend on the platform of the daemon.
+	Titles []string
+	// Processes are rows of the table. Each row has the same length as Titles.
+	Processes [][]string
+}
+
+// Rows returns Processes as maps keyed by Titles.
+func (t ProcessTable) Rows() []map[string]string {
+	rows := make([]map[string]string, len(t.Processes))
+	for i, process := range t.Processes {
+		row := make(map[string]string, len(t.Titles))
+		for j, title := range t.Titles {
+			if j < len(process) {
+				row[title] = process[j]
+			}
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+// Top executes the equivalent to a `compose top`
+//
+// If services is empty, processes of all containers of the project are listed.
+func (s *ComposeService) Top(ctx context.Context, services []string) ([]ProcessTable, error) {
+	c, ctx := s.begin(ctx)
+	defer c.end()
+	summary, err := c.service.Top(ctx, s.projectName, services)
+	if err != nil {
+		return nil, err
+	}
+	tables := make([]ProcessTable, len(summary))
+	for i, proc := range summary {
+		service, num, _ := resolveContainerName(proc.Name, s.projectName, c.project)
+		tables[i] = ProcessTable{
+			Service:     service,
+			Num:         num,
+			Container:   proc.Name,
+			ContainerID: proc.ID,
+			Titles:      proc.Titles,
+			Processes:   proc.Processes,
+		}
+	}
+	return tables, nil
+}
+
+// PortBinding is a container port published to the host.
+type PortBinding struct {
+	Service string
+	// Num is the replica number of the container.
+	Num         int
+	Container   string
+	ContainerID string
+	TargetPort  uint16
+	Protocol    string
+	HostIP      string
+	HostPort    int
+}
+
+// Port executes the equivalent to a `compose port`
+//
+// options.Protocol defaults to "tcp".
+// options.Index selects the replica. If it is 0, the running replica with the smallest number is used.
+// Only running containers are considered and one-off containers are never selected,
+// while compose alone may select one-off containers.
+//
+// Port does not wait for other calls.
+func (s *ComposeService) Port(ctx context.Context, service string, port uint16, options api.PortOptions) (PortBinding, error) {
+	c, ctx := s.begin(ctx)
+	defer c.end()
+	if options.Protocol == "" {
+		options.Protocol = "tcp"
+	}
+	containers, err := c.listReplicas(ctx, service, options.Index, false)
+	if err != nil {
+		return PortBinding{}, err
+	}
+	return toPortBinding(containers[0], port, options.Protocol)
+}
+
+func toPortBinding(container api.ContainerSummary, port uint16, protocol string) (PortBinding, error) {
+	var published []string
+	for _, p := range container.Publishers {
+		if p.PublishedPort == 0 {
+			continue
+		}
+		if p.TargetPort == int(port) && p.Protocol == protocol {
+			return PortBinding{
+				Service:     container.Service,
+				Num:         replicaNumber(container),
+				Container:   container.Name,
+				ContainerID: container.ID,
+				TargetPort:  port,
+				Protocol:    protocol,
+				HostIP:      p.URL,
+				HostPort:    p.PublishedPort,
+			}, nil
+		}
+		published = append(published, fmt.Sprintf("%d/%s", p.TargetPort, p.Protocol))
+	}
+	return PortBinding{}, fmt.Errorf("no port %d/%s for container %s: %s", port, protocol, container.Name, strings.Join(published, ", "))
+}
+
+// ContainerImage is an image used by a container.
+type ContainerImage struct {
+	Service string
+	// Num is the replica number. It is 0 for one-off containers.
+	Num       int
+	Container string
+	// ID is the image id, e.g. "sha256:...".
+	ID         string
+	Repository string
+	Tag        string
+	// Size is the size of the image in bytes.
+	Size int64
+}
+
+// Images executes the equivalent to a `compose images`
+//
+// If options.Services is empty, images of all containers of the project are listed.
+func (s *ComposeService) Images(ctx context.Context, options api.ImagesOptions) ([]ContainerImage, error) {
+	c, ctx := s.begin(ctx)
+	defer c.end()
+	summary, err := c.service.Images(ctx, s.projectName, options)
+	if err != nil {
+		return nil, err
+	}
+	return toContainerImages(summary, s.projectName, c.project), nil
+}
+
+func toContainerImages(summary []api.ImageSummary, projectName string, project *types.Project) []ContainerImage {
+	images := make([]ContainerImage, len(summary))
+	for i, img := range summary {
+		service, num, _ := resolveContainerName(img.ContainerName, projectName, project)
+		images[i] = ContainerImage{
+			Service:    service,
+			Num:        num,
+			Container:  img.ContainerName,
+			ID:         img.ID,
+			Repository: img.Repository,
+			Tag:        img.Tag,
+			Size:       img.Size,
+		}
+	}
+	return images
+}
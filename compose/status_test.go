@@ -0,0 +1,80 @@
+package compose
+
+import (
+	"testing"
+
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessTable_Rows(t *testing.T) {
+	table := ProcessTable{
+		Titles: []string{"UID", "PID", "CMD"},
+		Processes: [][]string{
+			{"root", "1", "sleep infinity"},
+			{"root", "7"},
+		},
+	}
+	assert.Equal(
+		t,
+		[]map[string]string{
+			{"UID": "root", "PID": "1", "CMD": "sleep infinity"},
+			{"UID": "root", "PID": "7"},
+		},
+		table.Rows(),
+	)
+}
+
+func TestToContainerImages(t *testing.T) {
+	project := loadFromString(prefixedServiceNameComposeYaml)
+	images := toContainerImages(
+		[]api.ImageSummary{
+			{ID: "sha256:0123", ContainerName: "example_compose-web-admin-2", Repository: "ubuntu", Tag: "jammy-20230624", Size: 77000000},
+			{ID: "sha256:0123", ContainerName: "custom-name", Repository: "ubuntu", Tag: "jammy-20230624", Size: 77000000},
+		},
+		"example_compose",
+		project,
+	)
+	expected := []ContainerImage{
+		{Service: "web-admin", Num: 2, Container: "example_compose-web-admin-2", ID: "sha256:0123", Repository: "ubuntu", Tag: "jammy-20230624", Size: 77000000},
+		{Service: "named", Num: 1, Container: "custom-name", ID: "sha256:0123", Repository: "ubuntu", Tag: "jammy-20230624", Size: 77000000},
+	}
+	if diff := cmp.Diff(expected, images); diff != "" {
+		t.Errorf("not equal. diff = %s", diff)
+	}
+}
+
+func TestToPortBinding(t *testing.T) {
+	assert := assert.New(t)
+
+	container := api.ContainerSummary{
+		ID:      "0123456789ab",
+		Name:    "example_compose-web-3",
+		Service: "web",
+		Labels:  map[string]string{api.ContainerNumberLabel: "3"},
+		Publishers: api.PortPublishers{
+			{URL: "0.0.0.0", TargetPort: 80, PublishedPort: 8080, Protocol: "tcp"},
+			{TargetPort: 81, Protocol: "tcp"},
+			{URL: "127.0.0.1", TargetPort: 53, PublishedPort: 5353, Protocol: "udp"},
+		},
+	}
+
+	binding, err := toPortBinding(container, 53, "udp")
+	assert.NoError(err)
+	assert.Equal(PortBinding{
+		Service:     "web",
+		Num:         3,
+		Container:   "example_compose-web-3",
+		ContainerID: "0123456789ab",
+		TargetPort:  53,
+		Protocol:    "udp",
+		HostIP:      "127.0.0.1",
+		HostPort:    5353,
+	}, binding)
+
+	_, err = toPortBinding(container, 53, "tcp")
+	assert.EqualError(err, "no port 53/tcp for container example_compose-web-3: 80/tcp, 53/udp")
+	_, err = toPortBinding(container, 81, "tcp")
+	assert.Error(err)
+}
@@ -10,4 +10,5 @@ docker compose --progress plain create --remove-orphans 2> 04_recreate.txt
 docker compose --progress plain --dry-run start 2> 05_restart-dryrun.txt
 docker compose --progress plain start 2> 06_restart.txt
 docker compose --progress plain down -v 2> 07_down.txt
-docker compose --progress plain -f nonexistent.yml create 2> 08_nonexistent_compose_yml.txt
\ No newline at end of file
+docker compose --progress plain -f nonexistent.yml create 2> 08_nonexistent_compose_yml.txt
+docker compose -f compose.yml -f additional.yml --progress plain pull 2> 09_pull.txt
\ No newline at end of file
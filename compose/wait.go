@@ -0,0 +1,286 @@
+package compose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/docker/compose/v2/pkg/api"
+)
+
+// WaitStatus is the status of a container observed by ComposeService.WaitHealthy.
+type WaitStatus string
+
+const (
+	WaitPending  WaitStatus = "pending"
+	WaitReady    WaitStatus = "ready"
+	WaitFailed   WaitStatus = "failed"
+	WaitTimedOut WaitStatus = "timed_out"
+)
+
+// WaitHealthyOptions is options for ComposeService.WaitHealthy.
+type WaitHealthyOptions struct {
+	// Interval is the interval of polling. Defaults to 500ms if zero or negative.
+	Interval time.Duration
+	// Timeout limits the time to wait. WaitHealthy waits until ctx is cancelled if zero or negative.
+	Timeout time.Duration
+}
+
+// ContainerWaitReport is the last observation of a replica.
+type ContainerWaitReport struct {
+	Service string
+	Num     int
+	// Container is the container name. It is empty if the replica has not been found.
+	Container string
+	// Condition is one of types.ServiceConditionStarted, types.ServiceConditionHealthy
+	// and types.ServiceConditionCompletedSuccessfully.
+	Condition string
+	Status    WaitStatus
+	State     string
+	Health    string
+	ExitCode  int
+	// Reason describes why the replica is not ready. It is empty if Status is WaitReady.
+	Reason string
+}
+
+// WaitReport is the result of ComposeService.WaitHealthy.
+type WaitReport struct {
+	// Containers is sorted by the order of dependencies, and then by the replica number.
+	Containers []ContainerWaitReport
+}
+
+// Ready reports whether all replicas are ready.
+func (r WaitReport) Ready() bool {
+	return !slices.ContainsFunc(r.Containers, func(c ContainerWaitReport) bool { return c.Status != WaitReady })
+}
+
+// Failed returns replicas whose Status is WaitFailed.
+func (r WaitReport) Failed() []ContainerWaitReport {
+	return r.filter(WaitFailed)
+}
+
+// TimedOut returns replicas whose Status is WaitTimedOut.
+func (r WaitReport) TimedOut() []ContainerWaitReport {
+	return r.filter(WaitTimedOut)
+}
+
+func (r WaitReport) filter(status WaitStatus) []ContainerWaitReport {
+	var out []ContainerWaitReport
+	for _, c := range r.Containers {
+		if c.Status == status {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (r WaitReport) err() error {
+	var errs []error
+	for _, c := range r.Containers {
+		if c.Status == WaitFailed || c.Status == WaitTimedOut {
+			errs = append(errs, fmt.Errorf("%s #%d %s: %s", c.Service, c.Num, c.Status, c.Reason))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WaitHealthy polls Ps until all replicas of services reach the condition required for them.
+// If services is empty, all services of the wrapped project are waited.
+//
+// Dependencies of services are also waited.
+// The condition of a service is the strongest one among its healthcheck and depends_on of the other waited services.
+// A service with an enabled healthcheck must be healthy. Other services must be running.
+// If any of waited services depends on it with service_completed_successfully, it must exit with code 0.
+//
+// A replica fails if it exits unexpectedly, becomes unhealthy,
+// or has no healthcheck while it is required to be healthy.
+// WaitHealthy keeps waiting other replicas after a failure so that the report covers all of them.
+// Replicas which are still pending when ctx is cancelled or options.Timeout elapses are reported as WaitTimedOut.
+//
+// The returned error is non nil if any replica failed or timed out, or Ps returned an error.
+//
+// WaitHealthy does not wait for other calls.
+func (s *ComposeService) WaitHealthy(ctx context.Context, services []string, options WaitHealthyOptions) (WaitReport, error) {
+	project := s.snapshot()
+
+	conditions, order, err := waitConditions(project, services)
+	if err != nil {
+		return WaitReport{}, err
+	}
+
+	interval := options.Interval
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+	waitCtx := ctx
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var report WaitReport
+	for {
+		containers, err := s.Ps(waitCtx, api.PsOptions{All: true, Services: order})
+		if err != nil {
+			if waitCtx.Err() == nil || len(report.Containers) == 0 {
+				return report, err
+			}
+			// Keep the last observation.
+		} else {
+			report = evaluateWait(project, conditions, order, containers)
+		}
+
+		if !slices.ContainsFunc(report.Containers, func(c ContainerWaitReport) bool { return c.Status == WaitPending }) {
+			return report, report.err()
+		}
+
+		select {
+		case <-waitCtx.Done():
+			for i, c := range report.Containers {
+				if c.Status == WaitPending {
+					report.Containers[i].Status = WaitTimedOut
+				}
+			}
+			if ctx.Err() != nil {
+				return report, errors.Join(ctx.Err(), report.err())
+			}
+			return report, report.err()
+		case <-ticker.C:
+		}
+	}
+}
+
+var conditionStrength = map[string]int{
+	types.ServiceConditionStarted:               0,
+	types.ServiceConditionHealthy:               1,
+	types.ServiceConditionCompletedSuccessfully: 2,
+}
+
+// waitConditions returns conditions for services and their dependencies,
+// and names of them ordered so that dependencies come first.
+func waitConditions(project *types.Project, services []string) (map[string]string, []string, error) {
+	var waited []types.ServiceConfig
+	err := project.WithServices(services, func(sc types.ServiceConfig) error {
+		waited = append(waited, sc)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conditions := make(map[string]string, len(waited))
+	order := make([]string, 0, len(waited))
+	for _, sc := range waited {
+		conditions[sc.Name] = types.ServiceConditionStarted
+		if sc.HealthCheck != nil && !sc.HealthCheck.Disable && len(sc.HealthCheck.Test) > 0 {
+			conditions[sc.Name] = types.ServiceConditionHealthy
+		}
+		order = append(order, sc.Name)
+	}
+	for _, sc := range waited {
+		for dep, d := range sc.DependsOn {
+			current, ok := conditions[dep]
+			if !ok {
+				continue
+			}
+			if conditionStrength[d.Condition] > conditionStrength[current] {
+				conditions[dep] = d.Condition
+			}
+		}
+	}
+	return conditions, order, nil
+}
+
+func evaluateWait(project *types.Project, conditions map[string]string, order []string, containers []api.ContainerSummary) WaitReport {
+	var report WaitReport
+	for _, serviceName := range order {
+		var replicas []ContainerWaitReport
+		for _, c := range containers {
+			if c.Service != serviceName || c.Labels[api.OneoffLabel] == "True" {
+				continue
+			}
+			num, _ := strconv.Atoi(c.Labels[api.ContainerNumberLabel])
+			replicas = append(replicas, evaluateContainer(conditions[serviceName], num, c))
+		}
+
+		scale := 1
+		if service, err := project.GetService(serviceName); err == nil && service.Deploy != nil && service.Deploy.Replicas != nil {
+			scale = int(*service.Deploy.Replicas)
+		}
+		for num := 1; num <= scale; num++ {
+			if slices.ContainsFunc(replicas, func(c ContainerWaitReport) bool { return c.Num == num }) {
+				continue
+			}
+			replicas = append(replicas, ContainerWaitReport{
+				Service:   serviceName,
+				Num:       num,
+				Condition: conditions[serviceName],
+				Status:    WaitPending,
+				Reason:    "container not found",
+			})
+		}
+
+		slices.SortFunc(replicas, func(i, j ContainerWaitReport) int { return i.Num - j.Num })
+		report.Containers = append(report.Containers, replicas...)
+	}
+	return report
+}
+
+func evaluateContainer(condition string, num int, c api.ContainerSummary) ContainerWaitReport {
+	r := ContainerWaitReport{
+		Service:   c.Service,
+		Num:       num,
+		Container: c.Name,
+		Condition: condition,
+		Status:    WaitPending,
+		State:     c.State,
+		Health:    c.Health,
+		ExitCode:  c.ExitCode,
+	}
+
+	exited := c.State == "exited" || c.State == "dead"
+	switch condition {
+	case types.ServiceConditionCompletedSuccessfully:
+		switch {
+		case exited && c.ExitCode == 0:
+			r.Status = WaitReady
+		case exited:
+			r.Status, r.Reason = WaitFailed, fmt.Sprintf("exited with code %d", c.ExitCode)
+		default:
+			r.Reason = fmt.Sprintf("state is %s", c.State)
+		}
+	case types.ServiceConditionHealthy:
+		switch {
+		case exited:
+			r.Status, r.Reason = WaitFailed, fmt.Sprintf("exited with code %d", c.ExitCode)
+		case c.Health == "healthy":
+			r.Status = WaitReady
+		case c.Health == "unhealthy":
+			r.Status, r.Reason = WaitFailed, "unhealthy"
+		case c.State == "running" && c.Health == "":
+			r.Status, r.Reason = WaitFailed, "no healthcheck configured"
+		case c.Health != "":
+			r.Reason = fmt.Sprintf("health is %s", c.Health)
+		default:
+			r.Reason = fmt.Sprintf("state is %s", c.State)
+		}
+	default:
+		switch {
+		case exited:
+			r.Status, r.Reason = WaitFailed, fmt.Sprintf("exited with code %d", c.ExitCode)
+		case c.State == "running":
+			r.Status = WaitReady
+		default:
+			r.Reason = fmt.Sprintf("state is %s", c.State)
+		}
+	}
+	return r
+}
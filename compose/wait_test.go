@@ -0,0 +1,103 @@
+package compose
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+const waitComposeYaml = `services:
+  app:
+    image: ubuntu:jammy-20230624
+    depends_on:
+      db:
+        condition: service_healthy
+      migrate:
+        condition: service_completed_successfully
+      cache:
+        condition: service_started
+  db:
+    image: ubuntu:jammy-20230624
+  migrate:
+    image: ubuntu:jammy-20230624
+  cache:
+    image: ubuntu:jammy-20230624
+    healthcheck:
+      test: ["CMD", "true"]
+  worker:
+    image: ubuntu:jammy-20230624
+    deploy:
+      replicas: 2
+`
+
+func waitContainer(service string, num int, state, health string, exitCode int) api.ContainerSummary {
+	return api.ContainerSummary{
+		Name:     "example_compose-" + service + "-" + strconv.Itoa(num),
+		Service:  service,
+		State:    state,
+		Health:   health,
+		ExitCode: exitCode,
+		Labels:   map[string]string{api.ContainerNumberLabel: strconv.Itoa(num), api.OneoffLabel: "False"},
+	}
+}
+
+func TestWaitConditions(t *testing.T) {
+	project := loadFromString(waitComposeYaml)
+
+	conditions, order, err := waitConditions(project, []string{"app"})
+	assert.NoError(t, err)
+	assert.Equal(t, "app", order[len(order)-1])
+	assert.ElementsMatch(t, []string{"app", "db", "migrate", "cache"}, order)
+	assert.Equal(t, map[string]string{
+		"app":     types.ServiceConditionStarted,
+		"db":      types.ServiceConditionHealthy,
+		"migrate": types.ServiceConditionCompletedSuccessfully,
+		// healthcheck is stronger than service_started.
+		"cache": types.ServiceConditionHealthy,
+	}, conditions)
+
+	_, _, err = waitConditions(project, []string{"nonexistent"})
+	assert.Error(t, err)
+}
+
+func TestEvaluateWait(t *testing.T) {
+	project := loadFromString(waitComposeYaml)
+	conditions, _, _ := waitConditions(project, nil)
+	order := []string{"db", "migrate", "cache", "worker"}
+
+	oneOff := waitContainer("worker", 3, "running", "", 0)
+	oneOff.Labels[api.OneoffLabel] = "True"
+
+	report := evaluateWait(project, conditions, order, []api.ContainerSummary{
+		waitContainer("db", 1, "running", "starting", 0),
+		waitContainer("migrate", 1, "exited", "", 1),
+		waitContainer("cache", 1, "running", "healthy", 0),
+		waitContainer("worker", 2, "running", "", 0),
+		oneOff,
+	})
+
+	expected := []ContainerWaitReport{
+		{Service: "db", Num: 1, Container: "example_compose-db-1", Condition: types.ServiceConditionHealthy, Status: WaitPending, State: "running", Health: "starting", Reason: "health is starting"},
+		{Service: "migrate", Num: 1, Container: "example_compose-migrate-1", Condition: types.ServiceConditionCompletedSuccessfully, Status: WaitFailed, State: "exited", ExitCode: 1, Reason: "exited with code 1"},
+		{Service: "cache", Num: 1, Container: "example_compose-cache-1", Condition: types.ServiceConditionHealthy, Status: WaitReady, State: "running", Health: "healthy"},
+		{Service: "worker", Num: 1, Condition: types.ServiceConditionStarted, Status: WaitPending, Reason: "container not found"},
+		{Service: "worker", Num: 2, Container: "example_compose-worker-2", Condition: types.ServiceConditionStarted, Status: WaitReady, State: "running"},
+	}
+
+	if diff := cmp.Diff(expected, report.Containers); diff != "" {
+		t.Errorf("not equal. diff = %s", diff)
+	}
+	assert.False(t, report.Ready())
+	assert.Len(t, report.Failed(), 1)
+	assert.Error(t, report.err())
+
+	report = evaluateWait(project, conditions, []string{"db"}, []api.ContainerSummary{
+		waitContainer("db", 1, "running", "", 0),
+	})
+	assert.Equal(t, WaitFailed, report.Containers[0].Status)
+	assert.Equal(t, "no healthcheck configured", report.Containers[0].Reason)
+}
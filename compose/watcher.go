@@ -0,0 +1,223 @@
+package compose
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchEvent is emitted by ProjectWatcher after files which the project depends on changed.
+type WatchEvent struct {
+	// Files are absolute paths of changed files, sorted.
+	Files []string
+	// Old is the project before the change.
+	Old *types.Project
+	// New is the reloaded project. It is nil if Err is non nil.
+	New *types.Project
+	// Diff is the difference from Old to New.
+	// It is empty if only contents of secret or config files changed, since the project itself is the same.
+	Diff ProjectDiff
+	// Err is non nil if reloading failed. Old remains the current project in that case.
+	Err error
+}
+
+// ProjectWatcherOptions is options for NewProjectWatcher.
+type ProjectWatcherOptions struct {
+	// Debounce delays reloading until no change is observed for it,
+	// so that a burst of writes, e.g. by an editor, results in a single reload.
+	// It defaults to 500ms.
+	Debounce time.Duration
+}
+
+// ProjectWatcher watches files which a project depends on and reloads the project through a LoaderProxy.
+//
+// Watched files are compose files, env_file of services, and files of secrets and configs.
+// The set of them is updated after each successful reload.
+// Compose files whose content is preloaded, e.g. by PreloadConfigDetails, are read again when they change.
+// Files which do not exist on disk are not watched.
+type ProjectWatcher struct {
+	loader  *LoaderProxy
+	options ProjectWatcherOptions
+}
+
+// NewProjectWatcher returns a new ProjectWatcher which reloads the project through loader.
+func NewProjectWatcher(loader *LoaderProxy, options ProjectWatcherOptions) *ProjectWatcher {
+	if options.Debounce <= 0 {
+		options.Debounce = 500 * time.Millisecond
+	}
+	return &ProjectWatcher{
+		loader:  loader,
+		options: options,
+	}
+}
+
+// Watch loads the project, then watches files until ctx is cancelled or onChange returns an error.
+//
+// onChange is called with an event after each reload, whether it succeeded or not.
+// It may apply the new project, e.g. by loading a new ComposeService and calling Up.
+// Changes observed while onChange is running are reported by the next call.
+//
+// Watch returns ctx.Err() if ctx is cancelled, or the error returned by onChange or the file watcher.
+func (w *ProjectWatcher) Watch(ctx context.Context, onChange func(ctx context.Context, event WatchEvent) error) error {
+	project, err := w.loader.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	files := dependentFiles(w.loader.ConfigDetails(), project)
+	dirs := map[string]bool{}
+	if err := updateWatchedDirs(watcher, dirs, files); err != nil {
+		return err
+	}
+
+	var (
+		changed = map[string]bool{}
+		timer   = time.NewTimer(w.options.Debounce)
+	)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-watcher.Errors:
+			return err
+		case event := <-watcher.Events:
+			if !slices.Contains(files, event.Name) || event.Op == fsnotify.Chmod {
+				continue
+			}
+			changed[event.Name] = true
+			timer.Reset(w.options.Debounce)
+		case <-timer.C:
+			event := WatchEvent{Files: sortedKeys(changed), Old: project}
+			changed = map[string]bool{}
+
+			event.New, event.Err = w.reload(ctx, event.Files)
+			if event.Err == nil {
+				event.Diff, event.Err = DiffProjects(project, event.New)
+			}
+			if event.Err != nil {
+				event.New = nil
+			} else {
+				project = event.New
+				files = dependentFiles(w.loader.ConfigDetails(), project)
+				if err := updateWatchedDirs(watcher, dirs, files); err != nil {
+					return err
+				}
+			}
+
+			if err := onChange(ctx, event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// reload reads changed compose files again if they are preloaded, then loads the project.
+func (w *ProjectWatcher) reload(ctx context.Context, changed []string) (*types.Project, error) {
+	details := w.loader.ConfigDetails()
+	reread := false
+	for i, file := range details.ConfigFiles {
+		if file.Filename == "" || len(file.Content) == 0 {
+			continue
+		}
+		if slices.Contains(changed, absPath("", file.Filename)) {
+			details.ConfigFiles[i].Content = nil
+			details.ConfigFiles[i].Config = nil
+			reread = true
+		}
+	}
+	if reread {
+		loaded, err := PreloadConfigDetails(details)
+		if err != nil {
+			return nil, err
+		}
+		w.loader.UpdateConfigDetails(loaded)
+	}
+	return w.loader.Load(ctx)
+}
+
+// dependentFiles returns absolute paths of existing files which project loaded by details depends on, sorted.
+func dependentFiles(details types.ConfigDetails, project *types.Project) []string {
+	var files []string
+	add := func(baseDir, path string) {
+		if path == "" {
+			return
+		}
+		path = absPath(baseDir, path)
+		if _, err := os.Stat(path); err != nil {
+			return
+		}
+		if !slices.Contains(files, path) {
+			files = append(files, path)
+		}
+	}
+
+	for _, file := range details.ConfigFiles {
+		// compose files are read relative to the current directory.
+		add("", file.Filename)
+	}
+	for _, service := range append(slices.Clone(project.Services), project.DisabledServices...) {
+		for _, envFile := range service.EnvFile {
+			add(project.WorkingDir, envFile)
+		}
+	}
+	for _, secret := range project.Secrets {
+		add(project.WorkingDir, secret.File)
+	}
+	for _, config := range project.Configs {
+		add(project.WorkingDir, config.File)
+	}
+	slices.Sort(files)
+	return files
+}
+
+func absPath(baseDir, path string) string {
+	if !filepath.IsAbs(path) && baseDir != "" {
+		path = filepath.Join(baseDir, path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return abs
+}
+
+// updateWatchedDirs watches parent directories of files and stops watching ones no longer needed.
+// Directories are watched instead of files, since editors often replace a file by renaming another onto it.
+func updateWatchedDirs(watcher *fsnotify.Watcher, dirs map[string]bool, files []string) error {
+	needed := map[string]bool{}
+	for _, file := range files {
+		needed[filepath.Dir(file)] = true
+	}
+	for _, dir := range sortedKeys(needed) {
+		if dirs[dir] {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			return err
+		}
+		dirs[dir] = true
+	}
+	for _, dir := range sortedKeys(dirs) {
+		if !needed[dir] {
+			_ = watcher.Remove(dir)
+			delete(dirs, dir)
+		}
+	}
+	return nil
+}
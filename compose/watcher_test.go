@@ -0,0 +1,119 @@
+package compose
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const watchedComposeYaml = `secrets:
+  token:
+    file: ./token.txt
+services:
+  app:
+    image: ubuntu:jammy-20230624
+    env_file: [app.env]
+    secrets: [token]
+`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	// replace the file by renaming as editors often do.
+	tmp := path + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, []byte(content), 0o644))
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+func TestProjectWatcher(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir := t.TempDir()
+	composeFile := filepath.Join(dir, "compose.yml")
+	writeFile(t, composeFile, watchedComposeYaml)
+	writeFile(t, filepath.Join(dir, "app.env"), "FOO=foo\n")
+	writeFile(t, filepath.Join(dir, "token.txt"), "secret")
+
+	loader, err := NewLoaderProxy(
+		"watched",
+		types.ConfigDetails{
+			WorkingDir:  dir,
+			ConfigFiles: []types.ConfigFile{{Filename: composeFile}},
+			Environment: types.NewMapping(os.Environ()),
+		},
+		nil,
+		nil,
+	)
+	require.NoError(err)
+	require.NoError(loader.PreloadConfigDetails())
+
+	project, err := loader.Load(context.Background())
+	require.NoError(err)
+	assert.Equal(
+		[]string{filepath.Join(dir, "app.env"), composeFile, filepath.Join(dir, "token.txt")},
+		dependentFiles(loader.ConfigDetails(), project),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan WatchEvent)
+	watchErr := make(chan error, 1)
+	watcher := NewProjectWatcher(loader, ProjectWatcherOptions{Debounce: 50 * time.Millisecond})
+	go func() {
+		watchErr <- watcher.Watch(ctx, func(ctx context.Context, event WatchEvent) error {
+			events <- event
+			return nil
+		})
+	}()
+	// wait for the watcher to start.
+	time.Sleep(100 * time.Millisecond)
+
+	next := func() WatchEvent {
+		t.Helper()
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event")
+			return WatchEvent{}
+		}
+	}
+
+	writeFile(t, filepath.Join(dir, "app.env"), "FOO=bar\n")
+	event := next()
+	require.NoError(event.Err)
+	assert.Equal([]string{filepath.Join(dir, "app.env")}, event.Files)
+	require.Len(event.Diff.Services, 1)
+	assert.Equal("app", event.Diff.Services[0].Name)
+	assert.Equal("bar", *event.New.Services[0].Environment["FOO"])
+
+	writeFile(t, composeFile, watchedComposeYaml+`  worker:
+    image: ubuntu:jammy-20230624
+`)
+	event = next()
+	require.NoError(event.Err)
+	assert.Equal([]string{composeFile}, event.Files)
+	require.Len(event.Diff.Services, 1)
+	assert.Equal(ServiceDiff{Name: "worker", Kind: ChangeAdded}, event.Diff.Services[0])
+
+	writeFile(t, filepath.Join(dir, "token.txt"), "rotated")
+	event = next()
+	require.NoError(event.Err)
+	assert.Equal([]string{filepath.Join(dir, "token.txt")}, event.Files)
+	assert.True(event.Diff.Empty())
+
+	writeFile(t, composeFile, "services: [")
+	event = next()
+	assert.Error(event.Err)
+	assert.Nil(event.New)
+
+	cancel()
+	assert.ErrorIs(<-watchErr, context.Canceled)
+}
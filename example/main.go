@@ -71,7 +71,7 @@ func main() {
 		panic(err)
 	}
 
-	_, err = service.DryRunMode(context.Background(), true)
+	service, err = service.DryRun()
 	if err != nil {
 		panic(err)
 	}